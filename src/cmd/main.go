@@ -1,15 +1,12 @@
 package main
 
 import (
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/web"
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/ws"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/server"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/configs"
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
 	"github.com/joho/godotenv"
 )
 
@@ -22,41 +19,16 @@ func init() {
 	}
 }
 
-func withAPIVersion(h http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-API-Version", configs.Version)
-		h.ServeHTTP(w, r)
-	}
-}
-
 func main() {
 	_ = godotenv.Load()
 
-	if os.Getenv("DEBUG") == "1" {
-		configs.Debug = true
-	}
-
-	log.Println("🌐 [main] Core gRPC: ", os.Getenv("CORE_GRPC_ADDRESS"))
-
-	grpcclient.Connect(os.Getenv("CORE_GRPC_ADDRESS"))
-	grpcclient.TestConnection()
-
-	// WebSocket
-	ws.EmitEventLoop()
-	http.HandleFunc("/ws", ws.HandleWebSocket)
-
-	// HTTP
-	http.HandleFunc("/web", withAPIVersion(web.HandleHTTP))
-
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	handlers.FillBattleIndex()
-	handlers.FillBots()
-	handlers.FillCaseImpact()
+	server.Setup()
 
 	log.Println("Web server running on port", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(http.ListenAndServe(":"+port, server.Handler()))
 }
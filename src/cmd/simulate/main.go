@@ -0,0 +1,239 @@
+// Command simulate rolls battles offline to show what a case or option does to margins.
+//
+// Cases are loaded from Core the way the server does (FillCaseImpact) or from a JSON
+// fixture in the getCases response shape. Every playerType and option combination is
+// rolled with the same RollRound/ResolveWinner code the server uses, and the report
+// lists expected and realised return-to-player, its variance and the tracked HE.
+//
+//	go run ./cmd/simulate -cases cases.json -battles 100000 -he 4.5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/provablyfair"
+	"github.com/joho/godotenv"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// optionSets - option combinations a battle can be created with
+var optionSets = [][]string{
+	{},
+	{"madness"},
+	{"jackpot"},
+	{"madness", "jackpot"},
+	{"equality"},
+}
+
+// result - totals of one case / playerType / options run
+type result struct {
+	battles  int
+	paid     int // battles with at least one paying player
+	draws    int
+	reRolls  int
+	tracker  *he.Tracker
+	sumRatio float64
+	sumSq    float64
+}
+
+func main() {
+	var (
+		fixture   = flag.String("cases", "", "JSON fixture in the getCases shape; empty loads cases from Core")
+		caseID    = flag.Int("case", 0, "simulate only this case id")
+		rounds    = flag.Int("rounds", 1, "cases opened per battle")
+		battles   = flag.Int("battles", 10000, "battles per playerType and option combination")
+		bots      = flag.Int("bots", 0, "bot seats per battle; bots pay nothing and their wins are not paid out")
+		houseEdge = flag.Float64("he", 0, "recent house edge used to select the payout curve")
+		curveName = flag.String("curve", "", "force a payout curve by name instead of -he")
+		rng       = flag.Int("rng", provablyfair.DefaultRNG, "RNG version")
+		types     = flag.String("types", "", "comma separated playerTypes; empty runs all")
+	)
+	flag.Parse()
+
+	if _, found := provablyfair.RNGs[*rng]; !found {
+		log.Fatalf("unknown rng %d", *rng)
+	}
+	curve := provablyfair.CurveForHE(*houseEdge)
+	if *curveName != "" {
+		c, found := provablyfair.Curves()[*curveName]
+		if !found {
+			log.Fatalf("unknown payout curve %q", *curveName)
+		}
+		curve = c
+	}
+
+	loadCases(*fixture)
+
+	caseIDs := make([]int, 0, len(handlers.CasesImpacted))
+	for id := range handlers.CasesImpacted {
+		if *caseID == 0 || id == *caseID {
+			caseIDs = append(caseIDs, id)
+		}
+	}
+	if len(caseIDs) == 0 {
+		log.Fatal("no cases to simulate")
+	}
+	sort.Ints(caseIDs)
+
+	playerTypes := make([]string, 0, len(handlers.PlayerTypeSlots))
+	if *types != "" {
+		for _, t := range strings.Split(*types, ",") {
+			if _, found := handlers.PlayerTypeSlots[t]; !found {
+				log.Fatalf("unknown playerType %q", t)
+			}
+			playerTypes = append(playerTypes, t)
+		}
+	} else {
+		for t := range handlers.PlayerTypeSlots {
+			playerTypes = append(playerTypes, t)
+		}
+		sort.Strings(playerTypes)
+	}
+
+	fmt.Printf("curve=%s(%d) rng=%d rounds=%d battles=%d bots=%d\n\n", curve.Name, curve.Factor, *rng, *rounds, *battles, *bots)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "case\tcasePrice\texpectedRTP%\tplayerType\toptions\tbattles\tdraws\treRolls\tRTP%\tstdDev%\tHE%\t")
+
+	for _, id := range caseIDs {
+		table := provablyfair.BuildOddsTable(handlers.CasesImpacted[id], curve)
+		if table.Total == 0 {
+			log.Printf("case %d has nothing to draw, skipped", id)
+			continue
+		}
+		cases := make([]int, *rounds)
+		for i := range cases {
+			cases[i] = id
+		}
+		cost := table.CasePrice.Float() * float64(*rounds)
+
+		for _, playerType := range playerTypes {
+			for _, options := range optionSets {
+				r := result{tracker: he.NewTracker()}
+				for i := 0; i < *battles; i++ {
+					simulateBattle(&r, playerType, options, cases, cost, *bots, *rng, curve)
+				}
+				r.tracker.CalHouseEdge()
+
+				var mean, variance float64
+				if r.paid > 0 {
+					mean = r.sumRatio / float64(r.paid)
+					variance = r.sumSq/float64(r.paid) - mean*mean
+				}
+				rtp := 0.0
+				if r.tracker.Income > 0 {
+					rtp = r.tracker.Expense / r.tracker.Income * 100
+				}
+				fmt.Fprintf(w, "%d\t%s\t%.2f\t%s\t%s\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\t\n",
+					id, table.CasePrice, table.RTP, playerType, optionsLabel(options),
+					r.battles, r.draws, r.reRolls, rtp, math.Sqrt(math.Max(variance, 0))*100, r.tracker.HE)
+			}
+		}
+	}
+	_ = w.Flush()
+}
+
+// loadCases fills handlers.CasesImpacted from the fixture or from Core.
+func loadCases(fixture string) {
+	if fixture != "" {
+		if _, err := handlers.LoadCasesFixture(fixture); err != nil {
+			log.Fatalf("load cases fixture: %v", err)
+		}
+		return
+	}
+
+	_ = godotenv.Load()
+	grpcclient.Connect(os.Getenv("CORE_GRPC_ADDRESS"))
+	if _, errR := handlers.FillCaseImpact(); errR.Code > 0 {
+		log.Fatalf("load cases from Core: %s (%d)", errR.Type, errR.Code)
+	}
+}
+
+// simulateBattle rolls one full battle and adds its money flow to r.
+func simulateBattle(r *result, playerType string, options []string, cases []int, cost float64, bots, rng int, curve provablyfair.PayoutCurve) {
+	serverSeed, serverSeedHash := provablyfair.GenerateServerSeed()
+	clientSeeds := make(map[string]interface{})
+	battle := &models.Battle{
+		PlayerType: playerType,
+		Options:    options,
+		Cases:      cases,
+		CaseCounts: len(cases),
+		Cost:       cost,
+		Slots:      make(map[string]models.Slot),
+		PFair: map[string]interface{}{
+			"serverSeed":     serverSeed,
+			"serverSeedHash": serverSeedHash,
+			"rng":            rng,
+			"clientSeed":     clientSeeds,
+			"nonceScheme":    provablyfair.CurrentNonceScheme,
+			"pickModel":      provablyfair.PickModelCurve,
+			"payoutCurve":    curve.Name,
+			"curveFactor":    curve.Factor,
+		},
+	}
+
+	// Bots take the last seats, like a creator filling the room with bots
+	seats := handlers.PlayerTypeSlots[playerType]
+	var income float64
+	for i := 1; i <= seats; i++ {
+		key := fmt.Sprintf("s%d", i)
+		slot := models.Slot{ID: i, Type: "Player"}
+		if i > seats-bots {
+			slot.Type = "Bot"
+		} else {
+			income += cost
+		}
+		battle.Slots[key] = slot
+		clientSeeds[key] = provablyfair.GenerateClientSeed()
+	}
+
+	handlers.NormalizeTeams(battle)
+	for roundKey := range cases {
+		handlers.RollRound(battle, roundKey)
+	}
+	handlers.FillJackpot(battle)
+	handlers.ResolveWinner(battle)
+
+	var expense float64
+	for _, key := range battle.Summery.Winners.Slots {
+		if battle.Slots[key].Type == "Player" {
+			expense += battle.Summery.Winners.SlotPrizes
+		}
+	}
+	for _, steps := range battle.Summery.Steps {
+		for _, step := range steps {
+			r.draws += 1 + len(step.Attempts)
+			for _, d := range step.Attempts {
+				if d.Reason == provablyfair.ReasonReRoll {
+					r.reRolls++
+				}
+			}
+		}
+	}
+
+	r.battles++
+	r.tracker.AddIncome(income)
+	r.tracker.AddExpense(expense)
+	if income > 0 {
+		ratio := expense / income
+		r.paid++
+		r.sumRatio += ratio
+		r.sumSq += ratio * ratio
+	}
+}
+
+// optionsLabel prints an option combination.
+func optionsLabel(options []string) string {
+	if len(options) == 0 {
+		return "-"
+	}
+	return strings.Join(options, "+")
+}
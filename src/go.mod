@@ -3,7 +3,6 @@ module github.com/Milad-Abooali/4in-cs2skin-g1/src
 go 1.24.4
 
 require (
-	github.com/Milad-Abooali/4in-cs2skin-g2/src v0.0.0-20250911040942-909a612c4873
 	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	google.golang.org/grpc v1.73.0
@@ -0,0 +1,126 @@
+// Package auth verifies user JWTs without a UM round-trip per request.
+//
+// Tokens are checked locally against a shared HS256 key or a JWKS when one is configured,
+// so bad and expired tokens never reach UM. Profiles UM returns are cached per token until
+// the cache TTL or the token expiry, whichever is first; UM is only asked on a miss.
+// Revoke, RevokeUser and Forget drop cached entries, and OnRevoke lets other parts react.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config is where and how long tokens are verified and cached.
+type Config struct {
+	Secret   string        // HS256 shared key, "" to skip
+	JWKSURL  string        // RS256 key set, "" to skip
+	CacheTTL time.Duration // longest a verified profile is reused; 0 turns the cache off
+}
+
+// DefaultConfig caches profiles for a minute and checks no signatures locally.
+func DefaultConfig() Config {
+	return Config{CacheTTL: 60 * time.Second}
+}
+
+// ConfigFromEnv reads AUTH_JWT_SECRET, AUTH_JWKS_URL and AUTH_CACHE_SECONDS.
+func ConfigFromEnv() Config {
+	c := DefaultConfig()
+	c.Secret = os.Getenv("AUTH_JWT_SECRET")
+	c.JWKSURL = os.Getenv("AUTH_JWKS_URL")
+	if v := os.Getenv("AUTH_CACHE_SECONDS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("[auth] invalid AUTH_CACHE_SECONDS %q, using %s", v, c.CacheTTL)
+		} else {
+			c.CacheTTL = time.Duration(n) * time.Second
+		}
+	}
+	return c
+}
+
+var (
+	cfgMu sync.RWMutex
+	cfg   = DefaultConfig()
+
+	// umVerify - the UM lookup behind a cache miss
+	umVerify = func(token string) (*umclient.Profile, error) {
+		return umclient.VerifyJWT(context.Background(), token)
+	}
+)
+
+// Setup replaces the config and empties the cache.
+func Setup(c Config) {
+	cfgMu.Lock()
+	cfg = c
+	cfgMu.Unlock()
+	resetKeys()
+	cacheReset()
+}
+
+func config() Config {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+	return cfg
+}
+
+// VerifyJWT is umclient.VerifyJWT behind the local check and the cache. A refused token is
+// a *umclient.Error, like UM's own refusals; each caller gets its own copy of the profile.
+func VerifyJWT(token string) (*umclient.Profile, error) {
+	c := config()
+	key := tokenKey(token)
+
+	if revoked(key) {
+		return nil, rejected()
+	}
+
+	verdict, exp := verifyLocal(token, c)
+	if verdict == localInvalid {
+		return nil, rejected()
+	}
+
+	if profile, ok := cacheGet(key); ok {
+		return &profile, nil
+	}
+
+	profile, err := lookup(key, func() (*umclient.Profile, error) {
+		profile, err := umVerify(token)
+		if err != nil {
+			return nil, err
+		}
+		cachePut(key, *profile, cacheUntil(c, exp))
+		return profile, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := *profile
+	return &out, nil
+}
+
+// rejected - the refusal of a token turned down here
+func rejected() error {
+	return &umclient.Error{Code: 1032, Type: "TOKEN_NOT_FOUND"}
+}
+
+// cacheUntil - the cache TTL, cut short by the token expiry
+func cacheUntil(c Config, exp time.Time) time.Time {
+	until := time.Now().Add(c.CacheTTL)
+	if !exp.IsZero() && exp.Before(until) {
+		until = exp
+	}
+	return until
+}
+
+// tokenKey - tokens are kept by hash only
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"sync"
+	"time"
+)
+
+// cacheMax - entries kept before expired ones are swept
+const cacheMax = 10000
+
+// revokedTTL - how long a revoked token without expiry stays refused
+const revokedTTL = 24 * time.Hour
+
+type cached struct {
+	profile umclient.Profile
+	until   time.Time
+}
+
+// call - one UM lookup shared by everyone missing the same token
+type call struct {
+	wg      sync.WaitGroup
+	profile *umclient.Profile
+	err     error
+}
+
+var (
+	cacheMu  sync.Mutex
+	cache    = make(map[string]cached)
+	revokedT = make(map[string]time.Time) // token key → refused until
+	inFlight = make(map[string]*call)
+
+	hooksMu sync.RWMutex
+	hooks   []func(userID int64)
+)
+
+func cacheGet(key string) (umclient.Profile, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	e, ok := cache[key]
+	if !ok {
+		return umclient.Profile{}, false
+	}
+	if time.Now().After(e.until) {
+		delete(cache, key)
+		return umclient.Profile{}, false
+	}
+	return e.profile, true
+}
+
+func cachePut(key string, profile umclient.Profile, until time.Time) {
+	if !until.After(time.Now()) {
+		return
+	}
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if len(cache) >= cacheMax {
+		sweep()
+	}
+	cache[key] = cached{profile: profile, until: until}
+}
+
+// sweep - drops expired entries; caller holds cacheMu
+func sweep() {
+	now := time.Now()
+	for k, e := range cache {
+		if now.After(e.until) {
+			delete(cache, k)
+		}
+	}
+	for k, until := range revokedT {
+		if now.After(until) {
+			delete(revokedT, k)
+		}
+	}
+}
+
+func cacheReset() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = make(map[string]cached)
+}
+
+// lookup runs fn once for every concurrent miss of the same token.
+func lookup(key string, fn func() (*umclient.Profile, error)) (*umclient.Profile, error) {
+	cacheMu.Lock()
+	if c, ok := inFlight[key]; ok {
+		cacheMu.Unlock()
+		c.wg.Wait()
+		return c.profile, c.err
+	}
+	c := &call{}
+	c.wg.Add(1)
+	inFlight[key] = c
+	cacheMu.Unlock()
+
+	c.profile, c.err = fn()
+	c.wg.Done()
+
+	cacheMu.Lock()
+	delete(inFlight, key)
+	cacheMu.Unlock()
+	return c.profile, c.err
+}
+
+func revoked(key string) bool {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	until, ok := revokedT[key]
+	if ok && time.Now().After(until) {
+		delete(revokedT, key)
+		return false
+	}
+	return ok
+}
+
+// Revoke refuses a token until it expires and drops its cached profile.
+func Revoke(token string) {
+	key := tokenKey(token)
+	until := time.Now().Add(revokedTTL)
+	if exp, ok := tokenExpiry(token); ok {
+		until = exp
+	}
+
+	cacheMu.Lock()
+	userID := cache[key].profile.ID
+	delete(cache, key)
+	revokedT[key] = until
+	cacheMu.Unlock()
+
+	if userID != 0 {
+		runHooks(userID)
+	}
+}
+
+// RevokeUser drops every cached profile of a user, so their next request asks UM again.
+func RevokeUser(userID int64) {
+	Forget(userID)
+	runHooks(userID)
+}
+
+// Forget drops the cached profiles of a user without telling the hooks, after a balance change.
+func Forget(userID int64) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for k, e := range cache {
+		if e.profile.ID == userID {
+			delete(cache, k)
+		}
+	}
+}
+
+// OnRevoke registers fn to run for the user of every revoked session.
+func OnRevoke(fn func(userID int64)) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, fn)
+}
+
+func runHooks(userID int64) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, fn := range hooks {
+		fn(userID)
+	}
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of verifyLocal.
+const (
+	localUnknown = iota // no key for it here, UM decides
+	localValid
+	localInvalid // bad signature or expired, refused without UM
+)
+
+// JWKS refresh limits
+const (
+	jwksMaxAge   = time.Hour
+	jwksMinRetry = time.Minute
+)
+
+var (
+	keysMu      sync.Mutex
+	keys        map[string]*rsa.PublicKey // kid → key
+	keysFetched time.Time
+	keysTried   time.Time
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Exp float64 `json:"exp"`
+}
+
+// verifyLocal checks the signature and expiry of a token with the configured keys.
+// It also returns the expiry, zero when the token carries none.
+func verifyLocal(token string, c Config) (int, time.Time) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return localUnknown, time.Time{}
+	}
+	var header jwtHeader
+	if !decodeSegment(parts[0], &header) {
+		return localUnknown, time.Time{}
+	}
+	var claims jwtClaims
+	if !decodeSegment(parts[1], &claims) {
+		return localUnknown, time.Time{}
+	}
+	var exp time.Time
+	if claims.Exp > 0 {
+		exp = time.Unix(int64(claims.Exp), 0)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return localUnknown, exp
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	verdict := localUnknown
+	switch {
+	case header.Alg == "HS256" && c.Secret != "":
+		mac := hmac.New(sha256.New, []byte(c.Secret))
+		mac.Write(signed)
+		verdict = localInvalid
+		if hmac.Equal(sig, mac.Sum(nil)) {
+			verdict = localValid
+		}
+	case header.Alg == "RS256" && c.JWKSURL != "":
+		key := jwksKey(c.JWKSURL, header.Kid)
+		if key == nil {
+			return localUnknown, exp
+		}
+		sum := sha256.Sum256(signed)
+		verdict = localInvalid
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) == nil {
+			verdict = localValid
+		}
+	}
+
+	// Expired - only trusted from a signature checked here
+	if verdict == localValid && !exp.IsZero() && time.Now().After(exp) {
+		verdict = localInvalid
+	}
+	return verdict, exp
+}
+
+// tokenExpiry reads exp without checking the signature.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var claims jwtClaims
+	if !decodeSegment(parts[1], &claims) || claims.Exp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(claims.Exp), 0), true
+}
+
+func decodeSegment(seg string, v interface{}) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(raw, v) == nil
+}
+
+// jwksKey returns the key of kid, fetching the set when it is stale or the kid is new.
+func jwksKey(url, kid string) *rsa.PublicKey {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	key, ok := keys[kid]
+	stale := time.Since(keysFetched) > jwksMaxAge
+	if (ok && !stale) || time.Since(keysTried) < jwksMinRetry {
+		return key
+	}
+
+	keysTried = time.Now()
+	fetched, err := fetchJWKS(url)
+	if err != nil {
+		log.Printf("[auth] JWKS %s: %v", url, err)
+		return key
+	}
+	keys = fetched
+	keysFetched = time.Now()
+	return keys[kid]
+}
+
+func resetKeys() {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = nil
+	keysFetched = time.Time{}
+	keysTried = time.Time{}
+}
+
+// fetchJWKS - the RSA keys of a JWKS document
+func fetchJWKS(url string) (map[string]*rsa.PublicKey, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		out[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return out, nil
+}
@@ -1,10 +1,11 @@
 package events
 
 type Event struct {
-	Target string
-	UserID int64
-	Type   string
-	Data   interface{}
+	Target   string
+	UserID   int64
+	BattleID int64
+	Type     string
+	Data     interface{}
 }
 
 var Bus = make(chan Event, 100)
@@ -21,3 +22,19 @@ func Emit(target string, eventType string, data interface{}) {
 
 	}
 }
+
+// EmitBattle sends an event about one battle, its data keyed by the battle ID.
+func EmitBattle(battleID int64, eventType string, data map[string]interface{}) {
+	data["battleId"] = battleID
+	ev := Event{
+		Target:   "battle",
+		BattleID: battleID,
+		Type:     eventType,
+		Data:     data,
+	}
+	select {
+	case Bus <- ev:
+	default:
+
+	}
+}
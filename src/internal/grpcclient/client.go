@@ -2,13 +2,16 @@ package grpcclient
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
 
-	pb "github.com/Milad-Abooali/4in-cs2skin-g2/src/proto"
+	pb "github.com/Milad-Abooali/4in-cs2skin-g1/src/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var client pb.DataServiceClient
@@ -41,6 +44,9 @@ func Connect(address string) {
 
 // SendQuery sends a raw SQL query to the Core with access token
 func SendQuery(query string) (*pb.QueryResponse, error) {
+	if client == nil {
+		return nil, errors.New("core gRPC is not connected")
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -52,6 +58,64 @@ func SendQuery(query string) (*pb.QueryResponse, error) {
 	})
 }
 
+// SendQueryArgs sends a query whose ? placeholders the Core binds, in order, to args.
+// Values never become part of the SQL text, so quotes in user data cannot break or change the query.
+func SendQueryArgs(query string, args ...interface{}) (*pb.QueryResponse, error) {
+	if client == nil {
+		return nil, errors.New("core gRPC is not connected")
+	}
+	params, err := QueryParams(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := os.Getenv("CORE_GRPC_TOKEN")
+
+	return client.Query(ctx, &pb.QueryRequest{
+		Token:  token,
+		Query:  query,
+		Params: params,
+	})
+}
+
+// QueryParams converts Go values to typed query parameters.
+// Times are sent as UTC "2006-01-02 15:04:05", the DATETIME format of the Core tables.
+func QueryParams(args ...interface{}) ([]*pb.QueryParam, error) {
+	params := make([]*pb.QueryParam, 0, len(args))
+	for i, arg := range args {
+		var p pb.QueryParam
+		switch v := arg.(type) {
+		case nil:
+			p.Value = &pb.QueryParam_NullValue{NullValue: structpb.NullValue_NULL_VALUE}
+		case string:
+			p.Value = &pb.QueryParam_StringValue{StringValue: v}
+		case []byte:
+			p.Value = &pb.QueryParam_BytesValue{BytesValue: v}
+		case bool:
+			p.Value = &pb.QueryParam_BoolValue{BoolValue: v}
+		case int:
+			p.Value = &pb.QueryParam_IntValue{IntValue: int64(v)}
+		case int32:
+			p.Value = &pb.QueryParam_IntValue{IntValue: int64(v)}
+		case int64:
+			p.Value = &pb.QueryParam_IntValue{IntValue: v}
+		case float32:
+			p.Value = &pb.QueryParam_DoubleValue{DoubleValue: float64(v)}
+		case float64:
+			p.Value = &pb.QueryParam_DoubleValue{DoubleValue: v}
+		case time.Time:
+			p.Value = &pb.QueryParam_StringValue{StringValue: v.UTC().Format("2006-01-02 15:04:05")}
+		default:
+			return nil, fmt.Errorf("query param %d: unsupported type %T", i+1, arg)
+		}
+		params = append(params, &p)
+	}
+	return params, nil
+}
+
 // TestConnection performs a simple test query to verify gRPC connectivity
 func TestConnection() {
 	resp, err := SendQuery("SELECT version();")
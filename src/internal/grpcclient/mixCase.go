@@ -1,105 +1,148 @@
 package grpcclient
 
 import (
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
 	"google.golang.org/protobuf/types/known/structpb"
+	"log"
+	"strconv"
 )
 
-type CaseWithItems map[string]interface{}
-
+// MergeCasesAndItems builds typed cases from the cases and case_items rows.
+// Items whose bounds or price cannot be read are left out, so the validator reports their range as a gap.
 func MergeCasesAndItems(
 	cases []*structpb.Struct,
 	items []*structpb.Struct,
-) map[int]CaseWithItems {
+) map[int]models.Case {
 
-	result := make(map[int]CaseWithItems)
+	result := make(map[int]models.Case)
 
 	// Make Cases
 	for _, c := range cases {
-		idField, ok := c.Fields["id"]
+		id, ok := intField(c, "id")
 		if !ok {
-			// log.Printf("[MergeCasesAndItems] case #%d has no 'id' field", i)
 			continue
 		}
-
-		id := int(idField.GetNumberValue())
-
-		caseMap := make(CaseWithItems)
-		for k, v := range c.Fields {
-			caseMap[k] = getProtoValue(v)
+		price, err := moneyField(c, "price")
+		if err != nil {
+			log.Printf("[MergeCasesAndItems] case %d: %v", id, err)
+			continue
+		}
+		weight, _ := numberField(c, "weight")
+
+		result[id] = models.Case{
+			ID:           id,
+			Name:         stringField(c, "name"),
+			Color:        stringField(c, "color"),
+			Price:        price,
+			Distribution: stringField(c, "distribution"),
+			Rarity:       stringField(c, "rarity"),
+			Weight:       weight,
+			Items:        []models.CaseItem{},
 		}
-
-		caseMap["items"] = make(map[int]map[string]interface{})
-		result[id] = caseMap
 	}
 
 	// Add Items to Cases
 	for _, it := range items {
-
-		caseIDField, ok := it.Fields["case_id"]
+		itemID, ok := intField(it, "id")
 		if !ok {
-			// log.Printf("[MergeCasesAndItems] item #%d has no 'case_id' field", j)
 			continue
 		}
-		itemIDField, ok := it.Fields["id"]
+		caseID, ok := intField(it, "case_id")
 		if !ok {
-			// log.Printf("[MergeCasesAndItems] item #%d has no 'id' field", j)
 			continue
 		}
-
-		caseID := int(caseIDField.GetNumberValue())
-		itemID := int(itemIDField.GetNumberValue())
-
-		caseMap, ok := result[caseID]
+		c, ok := result[caseID]
 		if !ok {
-			// log.Printf("[MergeCasesAndItems] item #%d references missing case_id=%d", j, caseID)
 			continue
 		}
 
-		itemsVal, ok := caseMap["items"]
-		if !ok {
-			// log.Printf("[MergeCasesAndItems] case_id=%d has no 'items' field", caseID)
+		minR, okMin := intField(it, "min_rand")
+		maxR, okMax := intField(it, "max_rand")
+		if !okMin || !okMax {
+			log.Printf("[MergeCasesAndItems] case %d item %d has no min_rand/max_rand", caseID, itemID)
 			continue
 		}
-
-		itemsMap, ok := itemsVal.(map[int]map[string]interface{})
-		if !ok {
-			// log.Printf("[MergeCasesAndItems] case_id=%d 'items' type assertion failed", caseID)
+		price, err := moneyField(it, "price")
+		if err != nil {
+			log.Printf("[MergeCasesAndItems] case %d item %d: %v", caseID, itemID, err)
 			continue
 		}
+		skinID, _ := intField(it, "item_id")
+
+		c.Items = append(c.Items, models.CaseItem{
+			ID:             itemID,
+			CaseID:         caseID,
+			ItemID:         skinID,
+			MinRand:        minR,
+			MaxRand:        maxR,
+			Price:          price,
+			Rarity:         stringField(it, "rarity"),
+			Color:          stringField(it, "color"),
+			MarketHashName: stringField(it, "market_hash_name"),
+			Category:       stringField(it, "category"),
+			Wear:           stringField(it, "wear"),
+		})
+		result[caseID] = c
+	}
 
-		itemMap := make(map[string]interface{})
-		for k, v := range it.Fields {
-			itemMap[k] = getProtoValue(v)
-		}
+	for id, c := range result {
+		c.SortItems()
+		result[id] = c
+	}
+	return result
+}
 
-		itemsMap[itemID] = itemMap
+// numberField reads a numeric column that may arrive as a number or a numeric string.
+func numberField(s *structpb.Struct, key string) (float64, bool) {
+	v, ok := s.Fields[key]
+	if !ok {
+		return 0, false
 	}
+	switch kind := v.Kind.(type) {
+	case *structpb.Value_NumberValue:
+		return kind.NumberValue, true
+	case *structpb.Value_StringValue:
+		f, err := strconv.ParseFloat(kind.StringValue, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
 
-	return result
+func intField(s *structpb.Struct, key string) (int, bool) {
+	f, ok := numberField(s, key)
+	return int(f), ok
 }
 
-func getProtoValue(v *structpb.Value) interface{} {
+// moneyField reads a DECIMAL column, exact when it arrives as a string.
+func moneyField(s *structpb.Struct, key string) (models.Money, error) {
+	v, ok := s.Fields[key]
+	if !ok {
+		return 0, fmt.Errorf("no %s", key)
+	}
+	switch kind := v.Kind.(type) {
+	case *structpb.Value_StringValue:
+		return models.ParseMoney(kind.StringValue)
+	case *structpb.Value_NumberValue:
+		return models.MoneyFromFloat(kind.NumberValue), nil
+	default:
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+}
+
+func stringField(s *structpb.Struct, key string) string {
+	v, ok := s.Fields[key]
+	if !ok {
+		return ""
+	}
 	switch kind := v.Kind.(type) {
 	case *structpb.Value_StringValue:
 		return kind.StringValue
 	case *structpb.Value_NumberValue:
-		return kind.NumberValue
-	case *structpb.Value_BoolValue:
-		return kind.BoolValue
-	case *structpb.Value_StructValue:
-		m := make(map[string]interface{})
-		for k, val := range kind.StructValue.Fields {
-			m[k] = getProtoValue(val)
-		}
-		return m
-	case *structpb.Value_ListValue:
-		var arr []interface{}
-		for _, val := range kind.ListValue.Values {
-			arr = append(arr, getProtoValue(val))
-		}
-		return arr
+		return strconv.FormatFloat(kind.NumberValue, 'f', -1, 64)
 	default:
-		return nil
+		return ""
 	}
 }
 
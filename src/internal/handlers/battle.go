@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -8,29 +9,47 @@ import (
 	"fmt"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/configs"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/apiapp"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/events"
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/he"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/store"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/wallet"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
-	"google.golang.org/protobuf/types/known/structpb"
 	"log"
 	"math/rand/v2"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
+// maxReRolls bounds the last-round tie break re-rolls of a single slot.
+const maxReRolls = 100
+
 var (
-	BattleIndex   = make(map[int64]*models.Battle)
+	// Store - where battles and seeds are persisted; main swaps it by BATTLE_STORE
+	Store         store.Store = store.NewCore()
+	BattleIndex               = make(map[int64]*models.Battle)
 	battleIndexMu sync.RWMutex
-	HE            float64
 )
 
+// PlayerTypeSlots - seats of every battle type
+var PlayerTypeSlots = map[string]int{
+	"1v1":     2,
+	"1v1v1":   3,
+	"1v1v1v1": 4,
+	"2v2":     4,
+	"1v6":     6,
+	"2v2v2":   6,
+	"3v3":     6,
+}
+
 // NewBattle - Handler
 func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
@@ -47,28 +66,47 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
+		return resR, umclient.AsHandlerError(err)
 	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+	userID := int(profile.ID)
+	displayName := profile.DisplayName
+
+	// Idempotency - a retried request gets the original response
+	idemKey, vErr, ok := optionalIdempotencyKey(data)
+	if !ok {
+		return resR, vErr
+	}
+	replay, release := claimIdempotencyKey("newBattle", userID, idemKey)
+	defer release()
+	if replay != nil {
+		return *replay, errR
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
-	displayName := profile["display_name"].(string)
 
-	balanceStr := fmt.Sprintf("%v", profile["balance"])
-	balance, err := strconv.ParseFloat(balanceStr, 64)
-	if err != nil {
-		balance = 0
+	balance := profile.Balance
+
+	chosenSeed, vErr, ok := optionalClientSeed(data)
+	if !ok {
+		return resR, vErr
+	}
+
+	rng := provablyfair.DefaultRNG
+	if _, exists := data["rng"]; exists {
+		v, vErr, ok := validate.RequireInt(data, "rng")
+		if !ok {
+			return resR, vErr
+		}
+		if _, found := provablyfair.RNGs[int(v)]; !found {
+			errR.Type = "INVALID_TYPE_OR_FORMAT"
+			errR.Code = 5003
+			errR.Data = map[string]interface{}{
+				"fieldName": "rng",
+				"fieldType": "eNum 1,2",
+			}
+			return resR, errR
+		}
+		rng = int(v)
 	}
 
 	options := castStringSlice(data["options"])
@@ -81,14 +119,17 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		CasesUi:    castCases(data["cases"]),
 		Players:    []int{},
 		CreatedBy:  0,
-		Status:     "Waiting Room",
-		StatusCode: 0,
 		Slots:      make(map[string]models.Slot),
 		PFair:      make(map[string]interface{}),
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 		Tracker:    he.NewTracker(),
 	}
+	_ = newBattle.Move(models.StatePending, "Pending Entry") // unlisted until the creator paid
+
+	// Lock Battle - it is on the index, open to other handlers, once saved
+	newBattle.MU.Lock()
+	defer newBattle.MU.Unlock()
 
 	// Cases
 	if casesArr, ok := data["cases"].([]interface{}); ok {
@@ -115,22 +156,7 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 							if caseData, ok := CasesImpacted[caseInt]; ok {
 
 								// Cal Price
-								var price float64
-								switch v := caseData["price"].(type) {
-								case float64:
-									price = v
-								case string:
-									p, err := strconv.ParseFloat(v, 64)
-									if err != nil {
-										log.Println("Invalid price value:", v)
-										continue
-									}
-									price = p
-								default:
-									log.Println("Unknown price type:", v)
-									continue
-								}
-								newBattle.Cost += utils.RoundToTwoDigits(price)
+								newBattle.Cost = utils.RoundToTwoDigits(newBattle.Cost + caseData.Price.Float())
 
 							} else {
 								errR.Type = "INVALID_CASE_ID"
@@ -166,6 +192,18 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		return resR, errR
 	}
 
+	// Fit Teams Slots
+	slots, found := PlayerTypeSlots[newBattle.PlayerType]
+	if !found {
+		errR.Type = "INVALID_TYPE_OR_FORMAT"
+		errR.Code = 5003
+		errR.Data = map[string]interface{}{
+			"fieldName": "playerType",
+			"fieldType": "eNum 0v0",
+		}
+		return resR, errR
+	}
+
 	// Check Balance
 	if balance < newBattle.Cost {
 		errR.Type = "INSUFFICIENT_BALANCE"
@@ -176,71 +214,7 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		}
 		return resR, errR
 	}
-	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		userID,
-		"game_loss",
-		"1",
-		newBattle.Cost,
-		"",
-		"Case Battle",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
-	}
-
-	// Add XP
-	AddXp, err := utils.AddXp(
-		userID,
-		int(1.54*newBattle.Cost),
-		"Create Battle",
-		"G1",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(AddXp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
-	}
-
-	// HE Tracks
-	newBattle.Tracker.AddIncome(newBattle.Cost)
 
-	// Fit Teams Slots
-	var slots int
-	switch data["playerType"] {
-	case "1v1":
-		slots = 2
-	case "1v1v1":
-		slots = 3
-	case "1v1v1v1", "2v2":
-		slots = 4
-	case "1v6", "2v2v2", "3v3":
-		slots = 6
-	default:
-		errR.Type = "INVALID_TYPE_OR_FORMAT"
-		errR.Code = 5003
-		errR.Data = map[string]interface{}{
-			"fieldName": "playerType",
-			"fieldType": "eNum 0v0",
-		}
-		return resR, errR
-	}
 	newBattle.Slots = make(map[string]models.Slot)
 	for i := 1; i <= slots; i++ {
 		key := fmt.Sprintf("s%d", i)
@@ -252,7 +226,7 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 	// Join Battle
 	newBattle.Players = append(newBattle.Players, userID)
 	newBattle.CreatedBy = userID
-	clientSeed := utils.MD5UserID(userID)
+	clientSeed := slotClientSeed(chosenSeed, userID)
 	newBattle.Slots["s1"] = models.Slot{
 		ID:          userID,
 		DisplayName: displayName,
@@ -260,55 +234,49 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		Type:        "Player",
 	}
 
-	// Provably Fair
-	serverSeed, serverSeedHash := provablyfair.GenerateServerSeed()
+	// Provably Fair - the creator chose the client seed against this committed hash
+	serverSeed, serverSeedHash := takeServerSeed(userID)
 	newBattle.PFair = map[string]interface{}{
 		"serverSeed":     serverSeed,
 		"serverSeedHash": serverSeedHash,
+		"rng":            rng,
 		"clientSeed": map[string]interface{}{
 			"s1": clientSeed,
 		},
 	}
 
-	// Save to DB
-	battleJSON, err := json.Marshal(newBattle)
-	if err != nil {
-		log.Println("failed to marshal battle:", err)
+	// Save to DB - pending, so the entry fee is referenced by the battle ID; a restart before it
+	// opens settles the entry instead of listing the battle
+	id, errR := Store.Create(newBattle, serverSeed, serverSeedHash)
+	if errR.Code > 0 {
 		return resR, errR
 	}
+	newBattle.ID = id
 
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`INSERT INTO g1_games (server_seed,server_seed_hash, game) 
-				VALUES ('%s', '%s', '%s')`,
-		serverSeed,
-		serverSeedHash,
-		string(battleJSON),
-	)
-
-	// gRPC Call Insert User
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "DB_DATA"
-		errR.Code = 1070
-		if res != nil {
-			errR.Data = res.Error
-		}
+	// Entry Reference - saved before the debit, so a restart settles the right one
+	newBattle.EntryRefs = map[string]string{"s1": entryReference(newBattle, "s1", userID)}
+	if errR = Store.Update(newBattle); errR.Code > 0 {
+		discardBattle(newBattle, "Canceled, entry not taken")
 		return resR, errR
 	}
 
-	// Extract inserted_id from nested struct
-	newBattle.Status = fmt.Sprintf(`Waiting for %d users`, rune(slots-1))
-	newBattle.StatusCode = 0
+	// Take Entry - refunded below unless the battle is opened
+	entry, errR := wallet.Reserve(userID, newBattle.Cost, newBattle.EntryRefs["s1"], "Case Battle")
+	if entry == nil {
+		discardBattle(newBattle, "Canceled, entry not taken")
+		return resR, errR
+	}
+	if errR = entry.AddXp(int(1.54*newBattle.Cost), "Create Battle"); errR.Code > 0 {
+		refundEntry(newBattle, "s1", entry, "create failed")
+		discardBattle(newBattle, "Canceled, entry refunded")
+		return resR, errR
+	}
 
-	dataDB := res.Data.GetFields()
-	id := int(dataDB["inserted_id"].GetNumberValue())
-	if id < 1 {
-		errR.Type = "DB_DATA"
-		errR.Code = 1070
+	if errR = moveBattle(newBattle, models.StateWaiting, fmt.Sprintf(`Waiting for %d users`, slots-1)); errR.Code > 0 {
+		refundEntry(newBattle, "s1", entry, "create failed")
+		discardBattle(newBattle, "Canceled, entry refunded")
 		return resR, errR
 	}
-	newBattle.ID = id
 
 	// Options : Private
 	if utils.InArray(newBattle.Options, "private") {
@@ -323,12 +291,20 @@ func NewBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 
 	var update, errV = UpdateBattle(newBattle)
 	if update != true {
+		// The row exists but never opened; keep it canceled with the refund in its log
+		refundEntry(newBattle, "s1", entry, "create failed")
+		discardBattle(newBattle, "Canceled, entry refunded")
 		return resR, errV
 	}
+	entry.Commit()
+
+	// HE Tracks
+	newBattle.Tracker.AddIncome(newBattle.Cost)
 
 	// Success
 	resR.Type = "newBattle"
-	resR.Data = newBattleResponse(BattleIndex[int64(id)])
+	resR.Data = newBattleResponse(newBattle)
+	rememberResult("newBattle", userID, idemKey, resR)
 	return resR, errR
 }
 
@@ -344,22 +320,11 @@ func CancelBattle(data map[string]interface{}) (models.HandlerOK, models.Handler
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
+	userID := int(profile.ID)
 
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
@@ -373,6 +338,10 @@ func CancelBattle(data map[string]interface{}) (models.HandlerOK, models.Handler
 		return resR, errR
 	}
 
+	// Lock Battle
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
 	// Is Owner
 	if userID != battle.CreatedBy {
 		errR.Type = "INVALID_CREDENTIALS"
@@ -381,7 +350,7 @@ func CancelBattle(data map[string]interface{}) (models.HandlerOK, models.Handler
 	}
 
 	// Check Status
-	if battle.StatusCode > 0 {
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -400,55 +369,39 @@ func CancelBattle(data map[string]interface{}) (models.HandlerOK, models.Handler
 	// Refound Process
 
 	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		userID,
-		"game_win",
-		"1",
-		battle.Cost,
-		"",
-		"Refound",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
+	creatorSlot := "s1"
+	for key, slot := range battle.Slots {
+		if slot.IsPlayer() && slot.ID == userID {
+			creatorSlot = key
 		}
-		return resR, errR
 	}
+	err = umclient.AddTransaction(context.Background(), umclient.Transaction{
+		UserID:      userID,
+		Type:        "game_win",
+		ReferenceID: wallet.RefundReference(seatEntryReference(battle, creatorSlot)),
+		Amount:      battle.Cost,
+		Description: "Refound",
+	})
+	if err != nil {
+		return resR, umclient.AsHandlerError(err)
+	}
+	auth.Forget(int64(userID))
 
 	// Add XP
-	AddXp, err := utils.AddXp(
-		userID,
-		int(1.54*battle.Cost)*-1,
-		"Cancel Battle",
-		"G1",
-	)
+	err = umclient.AddXp(context.Background(), userID, int(1.54*battle.Cost)*-1, "Cancel Battle", "G1")
 	if err != nil {
-		return resR, models.HandlerError{}
+		return resR, umclient.AsHandlerError(err)
 	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(AddXp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
+
+	if errR = moveBattle(battle, models.StateCanceled, "Canceled by user"); errR.Code > 0 {
 		return resR, errR
 	}
-
-	battle.Status = fmt.Sprintf(`Canceled by user`)
-	battle.StatusCode = -2
 	var update, errV = UpdateBattle(battle)
 	if update != true {
 		return resR, errV
 	}
 
-	go dropBattle(battle.ID, 0)
+	go dropBattle(battle.ID)
 
 	// Success
 	resR.Type = "cancelBattle"
@@ -475,30 +428,26 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
-	displayName := profile["display_name"].(string)
+	userID := int(profile.ID)
+	displayName := profile.DisplayName
 
-	balanceStr := fmt.Sprintf("%v", profile["balance"])
-	balance, err := strconv.ParseFloat(balanceStr, 64)
-	if err != nil {
-		balance = 0
+	// Idempotency - a retried request gets the original response
+	idemKey, vErr, ok := optionalIdempotencyKey(data)
+	if !ok {
+		return resR, vErr
+	}
+	replay, release := claimIdempotencyKey("join", userID, idemKey)
+	defer release()
+	if replay != nil {
+		return *replay, errR
 	}
 
+	balance := profile.Balance
+
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
 	if !ok {
@@ -511,6 +460,10 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 		return resR, errR
 	}
 
+	// Lock Battle
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
 	// Options : Private
 	if utils.InArray(battle.Options, "private") {
 		privateKey, vErr, ok := validate.RequireString(data, "privateKey", false)
@@ -524,7 +477,7 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 		}
 	}
 
-	if battle.StatusCode > 0 {
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -543,12 +496,17 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 		return resR, vErr
 	}
 	slotK := fmt.Sprintf("s%d", slotId)
-	if battle.Slots[slotK].Type != "Empty" {
+	if _, joining := battle.Joining[slotK]; joining || battle.Slots[slotK].Type != "Empty" {
 		errR.Type = "SLOT_IS_NOT_EMPTY"
 		errR.Code = 1027
 		return resR, errR
 	}
 
+	chosenSeed, vErr, ok := optionalClientSeed(data)
+	if !ok {
+		return resR, vErr
+	}
+
 	// Check Balance
 	if balance < battle.Cost {
 		errR.Type = "INSUFFICIENT_BALANCE"
@@ -560,66 +518,46 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 		return resR, errR
 	}
 
-	// Add Transaction
-	Transaction, err := utils.AddTransaction(
-		userID,
-		"game_loss",
-		"1",
-		battle.Cost,
-		"",
-		"Case Battle",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
+	// Joining - the seat and its entry reference are saved before the debit, so a restart settles it
+	if errR = markJoining(battle, slotK, userID, entryReference(battle, slotK, userID)); errR.Code > 0 {
 		return resR, errR
 	}
 
-	// Add XP
-	AddXp, err := utils.AddXp(
-		userID,
-		int(1.54*battle.Cost),
-		"Join Battle",
-		"G1",
-	)
-	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType = utils.SafeExtractErrorStatus(AddXp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
+	// Take Entry - refunded below unless the seat is saved
+	entry, errR := wallet.Reserve(userID, battle.Cost, battle.EntryRefs[slotK], "Case Battle")
+	if entry == nil {
+		unmarkJoining(battle, slotK)
+		if errR.Type == "UM_UNAVAILABLE" {
+			// The debit may be booked and is then refunded in the background
+			nextEntryAttempt(battle, slotK, userID)
+		}
+		if errV := Store.Update(battle); errV.Code > 0 {
+			log.Printf("[Join] battle %d: %s (%d)", battle.ID, errV.Type, errV.Code)
 		}
 		return resR, errR
 	}
-
-	// HE Tracks
-	if battle.Tracker == nil {
-		battle.Tracker = he.NewTracker()
+	if errR = entry.AddXp(int(1.54*battle.Cost), "Join Battle"); errR.Code > 0 {
+		unmarkJoining(battle, slotK)
+		refundEntry(battle, slotK, entry, "join failed")
+		return resR, errR
 	}
-	battle.Tracker.AddIncome(battle.Cost)
 
-	// Join Battle
-	clientSeed := utils.MD5UserID(userID)
+	// Join Battle - kept to undo if the seat is not saved
+	prevSlot := battle.Slots[slotK]
+	prevPlayers := battle.Players
+	prevState, prevStatus, prevCode, prevStateLog := battle.State, battle.Status, battle.StatusCode, battle.StateLog
+	clientSeed := slotClientSeed(chosenSeed, userID)
 	team := battle.Slots[slotK].Team
 	battle.Slots[slotK] = models.Slot{
 		ID:          userID,
 		DisplayName: displayName,
 		ClientSeed:  clientSeed,
-		Type:        "Players",
+		Type:        "Player",
 		Team:        team,
 	}
 	battle.Players = append(battle.Players, userID)
 	AddClientSeed(battle.PFair, slotK, clientSeed)
+	delete(battle.Joining, slotK)
 
 	// update battle
 	AddLog(battle, "join", int64(userID))
@@ -630,24 +568,31 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 			emptyCount++
 		}
 	}
+	state, statusText := seatStatus(emptyCount)
+	errV := moveBattle(battle, state, statusText)
+	if errV.Code == 0 {
+		_, errV = UpdateBattle(battle)
+	}
+	if errV.Code > 0 {
+		// Give the seat back before refunding, so the battle log shows both; the move was never saved
+		battle.Slots[slotK] = prevSlot
+		battle.Players = prevPlayers
+		RemoveClientSeed(battle.PFair, slotK)
+		battle.State, battle.Status, battle.StatusCode, battle.StateLog = prevState, prevStatus, prevCode, prevStateLog
+		unmarkJoining(battle, slotK)
+		refundEntry(battle, slotK, entry, "join failed")
+		return resR, errV
+	}
+	entry.Commit()
+
+	// HE Tracks
+	if battle.Tracker == nil {
+		battle.Tracker = he.NewTracker()
+	}
+	battle.Tracker.AddIncome(battle.Cost)
+
 	if emptyCount == 0 {
-		// Force To Roll
-		battle.Status = "Start Rolling"
-		battle.StatusCode = 0
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
-		go func(bid int) {
-			Roll(int64(battle.ID), 0)
-		}(battle.ID)
-	} else {
-		battle.Status = fmt.Sprintf(`Waiting for %d users`, emptyCount)
-		battle.StatusCode = 0
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
+		scheduleRoll(int64(battle.ID), 0, len(battle.Cases), Pacing.StartDelay)
 	}
 
 	// Success
@@ -656,6 +601,7 @@ func Join(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 		"emptySlots": emptyCount,
 		"clientSeed": clientSeed,
 	}
+	rememberResult("join", userID, idemKey, resR)
 	return resR, errR
 }
 
@@ -678,23 +624,12 @@ func ChangeSeat(data map[string]interface{}) (models.HandlerOK, models.HandlerEr
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
-	displayName := profile["display_name"].(string)
+	userID := int(profile.ID)
+	displayName := profile.DisplayName
 
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
@@ -712,7 +647,7 @@ func ChangeSeat(data map[string]interface{}) (models.HandlerOK, models.HandlerEr
 	battle.MU.Lock()
 	defer battle.MU.Unlock()
 
-	if battle.StatusCode > 0 {
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -721,12 +656,16 @@ func ChangeSeat(data map[string]interface{}) (models.HandlerOK, models.HandlerEr
 	// Get Current Slot
 	var oldSlot string
 	for key, slot := range battle.Slots {
-		if slot.ID == userID {
+		if slot.IsPlayer() && slot.ID == userID {
 			oldSlot = key
 			break
 		}
 	}
-	log.Printf("Old slot key for userID %d : %s:", userID, oldSlot)
+	if oldSlot == "" {
+		errR.Type = "NOT_JOINED"
+		errR.Code = 5012
+		return resR, errR
+	}
 
 	// Check Slot
 	slotId, vErr, ok := validate.RequireInt(data, "slotId")
@@ -734,36 +673,52 @@ func ChangeSeat(data map[string]interface{}) (models.HandlerOK, models.HandlerEr
 		return resR, vErr
 	}
 	slotK := fmt.Sprintf("s%d", slotId)
-	if battle.Slots[slotK].Type != "Empty" {
+	if _, joining := battle.Joining[slotK]; joining || battle.Slots[slotK].Type != "Empty" {
 		errR.Type = "SLOT_IS_NOT_EMPTY"
 		errR.Code = 1027
 		return resR, errR
 	}
-	log.Printf("Move to %s:", slotK)
+
+	chosenSeed, vErr, ok := optionalClientSeed(data)
+	if !ok {
+		return resR, vErr
+	}
+	if chosenSeed == "" {
+		chosenSeed = battle.Slots[oldSlot].ClientSeed
+	}
+
+	// Kept to undo if the move is not saved
+	prevOld, prevNew := battle.Slots[oldSlot], battle.Slots[slotK]
+	prevRef, hadRef := battle.EntryRefs[oldSlot]
+	prevState, prevStatus, prevCode, prevStateLog := battle.State, battle.Status, battle.StatusCode, battle.StateLog
 
 	// Join New Slot
-	clientSeed := utils.MD5UserID(userID)
-	team := battle.Slots[slotK].Team
+	clientSeed := slotClientSeed(chosenSeed, userID)
 	battle.Slots[slotK] = models.Slot{
 		ID:          userID,
 		DisplayName: displayName,
 		ClientSeed:  clientSeed,
-		Type:        "Players",
-		Team:        team,
+		Type:        "Player",
+		Team:        prevNew.Team,
 	}
 	AddClientSeed(battle.PFair, slotK, clientSeed)
 
 	// Clear Old Slot
-	team = battle.Slots[oldSlot].Team
 	battle.Slots[oldSlot] = models.Slot{
 		ID:          0,
 		DisplayName: "",
 		ClientSeed:  "",
 		Type:        "Empty",
-		Team:        team,
+		Team:        prevOld.Team,
 	}
 	RemoveClientSeed(battle.PFair, oldSlot)
 
+	// The entry fee goes with the player
+	if hadRef {
+		battle.EntryRefs[slotK] = prevRef
+		delete(battle.EntryRefs, oldSlot)
+	}
+
 	// update battle
 	AddLog(battle, "changeSeat", int64(userID))
 
@@ -773,24 +728,26 @@ func ChangeSeat(data map[string]interface{}) (models.HandlerOK, models.HandlerEr
 			emptyCount++
 		}
 	}
+	state, statusText := seatStatus(emptyCount)
+	errV := moveBattle(battle, state, statusText)
+	if errV.Code == 0 {
+		_, errV = UpdateBattle(battle)
+	}
+	if errV.Code > 0 {
+		// Back to the old seat; nothing of the move was saved
+		battle.Slots[oldSlot], battle.Slots[slotK] = prevOld, prevNew
+		AddClientSeed(battle.PFair, oldSlot, prevOld.ClientSeed)
+		RemoveClientSeed(battle.PFair, slotK)
+		if hadRef {
+			battle.EntryRefs[oldSlot] = prevRef
+			delete(battle.EntryRefs, slotK)
+		}
+		battle.State, battle.Status, battle.StatusCode, battle.StateLog = prevState, prevStatus, prevCode, prevStateLog
+		return resR, errV
+	}
 	if emptyCount == 0 {
 		// Force To Roll
-		battle.Status = "Start Rolling"
-		battle.StatusCode = 0
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
-		go func(bid int) {
-			Roll(int64(battle.ID), 0)
-		}(battle.ID)
-	} else {
-		battle.Status = fmt.Sprintf(`Waiting for %d users`, emptyCount)
-		battle.StatusCode = 0
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
+		scheduleRoll(int64(battle.ID), 0, len(battle.Cases), Pacing.StartDelay)
 	}
 
 	// Success
@@ -814,85 +771,25 @@ func GetBattleHistory(data map[string]interface{}) (models.HandlerOK, models.Han
 		return resR, vErr
 	}
 
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`SELECT game FROM g1_games WHERE id = %d`,
-		battleID,
-	)
-
-	// gRPC Call
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "PROFILE_GRPC_ERROR"
-		errR.Code = 1033
-		if res != nil {
-			errR.Data = res.Error
-		}
-		return resR, errR
-	}
-
-	// Extract gRPC struct
-	dataDB := res.Data.GetFields()
-
-	// DB result rows count
-	exist := dataDB["count"].GetNumberValue()
-	if exist == 0 {
-		errR.Type = "Battle_NOT_FOUND"
-		errR.Code = 1035
-		return resR, errR
-	}
-
-	// Get rows
-	rows := dataDB["rows"].GetListValue().Values
-	if len(rows) == 0 {
-		errR.Type = "Battle_NOT_FOUND"
-		errR.Code = 1035
+	battle, errR := loadBattle(battleID)
+	if errR.Code > 0 {
 		return resR, errR
 	}
 
-	row := rows[0].GetStructValue()
-	if row == nil {
-		errR.Type = "BATTLE_ROW_EMPTY"
-		errR.Code = 1038
-		return resR, errR
-	}
-
-	fields := row.GetFields()
-
-	battleVal := fields["game"]
-	battleStr := battleVal.GetStringValue()
-
-	var battleMap models.Battle
-
-	if strings.HasPrefix(battleStr, "{") {
-		if err := json.Unmarshal([]byte(battleStr), &battleMap); err != nil {
-			errR.Type = "BATTLE_JSON_ERROR"
-			errR.Code = 1036
-			return resR, errR
-		}
-	} else {
-		unquoted, err := strconv.Unquote(battleStr)
-		if err != nil {
-			errR.Type = "BATTLE_JSON_DECODE_ERROR"
-			errR.Code = 1037
-			return resR, errR
-		}
-
-		if err := json.Unmarshal([]byte(unquoted), &battleMap); err != nil {
-			errR.Type = "BATTLE_JSON_ERROR"
-			errR.Code = 1036
-			return resR, errR
-		}
-	}
-
 	// @todo - remove some items
 
 	// Success
 	resR.Type = "getBattleHistory"
-	resR.Data = ClientBattle(&battleMap)
+	resR.Data = ClientBattle(battle)
 	return resR, errR
 }
 
+// loadBattle - Battle Helper
+// reads a battle (live or archived) from the store.
+func loadBattle(battleID int64) (*models.Battle, models.HandlerError) {
+	return Store.Load(int(battleID))
+}
+
 // GetLiveBattles - Handler
 func GetLiveBattles(_ map[string]interface{}) (models.HandlerOK, models.HandlerError) {
 	var (
@@ -902,7 +799,7 @@ func GetLiveBattles(_ map[string]interface{}) (models.HandlerOK, models.HandlerE
 
 	// Success
 	resR.Type = "getLiveBattles"
-	resR.Data = ClientBattleIndex(BattleIndex)
+	resR.Data = ClientBattleIndex()
 	return resR, errR
 }
 
@@ -927,82 +824,16 @@ func GetBattleAdmin(data map[string]interface{}) (models.HandlerOK, models.Handl
 		return resR, vErr
 	}
 
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`SELECT game FROM g1_games WHERE id = %d`,
-		battleID,
-	)
-
-	// gRPC Call
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "PROFILE_GRPC_ERROR"
-		errR.Code = 1033
-		if res != nil {
-			errR.Data = res.Error
-		}
-		return resR, errR
-	}
-
-	// Extract gRPC struct
-	dataDB := res.Data.GetFields()
-
-	// DB result rows count
-	exist := dataDB["count"].GetNumberValue()
-	if exist == 0 {
-		errR.Type = "Battle_NOT_FOUND"
-		errR.Code = 1035
-		return resR, errR
-	}
-
-	// Get rows
-	rows := dataDB["rows"].GetListValue().Values
-	if len(rows) == 0 {
-		errR.Type = "Battle_NOT_FOUND"
-		errR.Code = 1035
-		return resR, errR
-	}
-
-	row := rows[0].GetStructValue()
-	if row == nil {
-		errR.Type = "BATTLE_ROW_EMPTY"
-		errR.Code = 1038
+	battle, errR := loadBattle(battleID)
+	if errR.Code > 0 {
 		return resR, errR
 	}
 
-	fields := row.GetFields()
-
-	battleVal := fields["game"]
-	battleStr := battleVal.GetStringValue()
-
-	var battleMap map[string]interface{}
-
-	if strings.HasPrefix(battleStr, "{") {
-		if err := json.Unmarshal([]byte(battleStr), &battleMap); err != nil {
-			errR.Type = "BATTLE_JSON_ERROR"
-			errR.Code = 1036
-			return resR, errR
-		}
-	} else {
-		unquoted, err := strconv.Unquote(battleStr)
-		if err != nil {
-			errR.Type = "BATTLE_JSON_DECODE_ERROR"
-			errR.Code = 1037
-			return resR, errR
-		}
-
-		if err := json.Unmarshal([]byte(unquoted), &battleMap); err != nil {
-			errR.Type = "BATTLE_JSON_ERROR"
-			errR.Code = 1036
-			return resR, errR
-		}
-	}
-
 	// @todo - remove some items
 
 	// Success
 	resR.Type = "getBattleAdmin"
-	resR.Data = battleMap
+	resR.Data = battle
 	return resR, errR
 }
 
@@ -1022,9 +853,22 @@ func GetLiveBattlesAdmin(data map[string]interface{}) (models.HandlerOK, models.
 		return resR, errR
 	}
 
+	// Battles are encoded under their own lock, the response is sent after
+	battles := make(map[int64]json.RawMessage)
+	for _, b := range indexedBattles() {
+		b.MU.Lock()
+		raw, err := json.Marshal(b)
+		b.MU.Unlock()
+		if err != nil {
+			log.Printf("[GetLiveBattlesAdmin] battle %d: %v", b.ID, err)
+			continue
+		}
+		battles[int64(b.ID)] = raw
+	}
+
 	// Success
 	resR.Type = "getLiveBattles"
-	resR.Data = BattleIndex
+	resR.Data = battles
 	return resR, errR
 }
 
@@ -1045,13 +889,37 @@ func SetBattle(id int64, b *models.Battle) {
 
 // DeleteBattle - Safe Battle Actions
 func DeleteBattle(id int64) {
-	AddLog(BattleIndex[id], "archive", int64(0))
+	if b, ok := GetBattle(id); ok {
+		b.MU.Lock()
+		AddLog(b, "archive", int64(0))
+		b.MU.Unlock()
+	}
 
 	battleIndexMu.Lock()
 	defer battleIndexMu.Unlock()
 	delete(BattleIndex, id)
 }
 
+// BattleCount - Safe Battle Actions
+func BattleCount() int {
+	battleIndexMu.RLock()
+	defer battleIndexMu.RUnlock()
+	return len(BattleIndex)
+}
+
+// indexedBattles - Safe Battle Actions
+// lists the live battles. The index lock is released before any battle is locked,
+// as UpdateBattle takes them in the other order.
+func indexedBattles() []*models.Battle {
+	battleIndexMu.RLock()
+	defer battleIndexMu.RUnlock()
+	battles := make([]*models.Battle, 0, len(BattleIndex))
+	for _, b := range BattleIndex {
+		battles = append(battles, b)
+	}
+	return battles
+}
+
 // SetSlotTeam - Battle Helper
 func SetSlotTeam(b *models.Battle, slotKey string, team int) {
 	if slot, ok := b.Slots[slotKey]; ok {
@@ -1113,48 +981,14 @@ func castCases(val interface{}) []map[string]int {
 
 // UpdateBattle - Battle Helper
 func UpdateBattle(battle *models.Battle) (bool, models.HandlerError) {
-	var (
-		errR models.HandlerError
-		bID  = battle.ID
-	)
+	var errR models.HandlerError
 	battle.UpdatedAt = time.Now()
-	battleJSON, err := json.Marshal(battle)
-	if err != nil {
-		errR.Type = "json.Marshal(battle)"
-		errR.Code = 1027
+	if errR = Store.Update(battle); errR.Code > 0 {
 		return false, errR
 	}
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`Update g1_games SET game = '%s' WHERE id = %d`,
-		string(battleJSON),
-		bID,
-	)
 
-	// gRPC Call
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "PROFILE_GRPC_ERROR"
-		errR.Code = 1033
-		if res != nil {
-			errR.Data = res.Error
-		}
-		return false, errR
-	}
-
-	// Extract gRPC struct
-	dataDB := res.Data.GetFields()
-
-	// DB result rows count
-	exist := dataDB["rows_affected"].GetNumberValue()
-	if exist == 0 {
-		errR.Type = "USER_NOT_FOUND"
-		errR.Code = 1035
-		return false, errR
-	}
-
-	// Add To Battle Index
-	SetBattle(int64(battle.ID), battle)
+	// Add To Battle Index
+	SetBattle(int64(battle.ID), battle)
 
 	return true, errR
 }
@@ -1178,7 +1012,7 @@ func newBattleResponse(b *models.Battle) models.BattleCreated {
 		Slots:      slots,
 		Status:     b.Status,
 		StatusCode: b.StatusCode,
-		Summery:    b.Summery,
+		Summery:    cloneSummery(b.Summery),
 		CreatedAt:  b.CreatedAt,
 		PrivateKey: b.PrivateKey,
 	}
@@ -1186,55 +1020,36 @@ func newBattleResponse(b *models.Battle) models.BattleCreated {
 
 // FillBattleIndex - Battle Helper
 func FillBattleIndex() (bool, models.HandlerError) {
-	var (
-		errR      models.HandlerError
-		dbBattles *structpb.ListValue
-	)
-
 	log.Println("Fill BattleIndex..")
 
-	// Sanitize and build query
-	query := `SELECT game FROM g1_games WHERE is_live=1`
-
-	// gRPC Call
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "PROFILE_GRPC_ERROR"
-		errR.Code = 1033
-		if res != nil {
-			errR.Data = res.Error
-		}
-		return false, errR
-	}
-	// Extract gRPC struct
-	dataDB := res.Data.GetFields()
-	// DB result rows count
-	exist := dataDB["count"].GetNumberValue()
-	if exist == 0 {
-		errR.Type = "DB_DATA"
-		errR.Code = 1070
+	battles, errR := Store.LoadLive()
+	if errR.Code > 0 {
 		return false, errR
 	}
-	// DB result rows get fields
-	dbBattles = dataDB["rows"].GetListValue()
 
-	for idx, row := range dbBattles.Values {
-		structRow := row.GetStructValue()
-		battleJSON := structRow.Fields["game"].GetStringValue() // JSON string
+	for idx, b := range battles {
+		key := int64(b.ID)
+		if key == 0 {
+			key = int64(idx + 1)
+		}
 
-		var b models.Battle
-		err := json.Unmarshal([]byte(battleJSON), &b)
-		if err != nil {
-			log.Println("Failed to unmarshal battle:", err)
+		// Never opened: RecoverBattles settles the entry, it is not listed
+		if b.CurrentState() == models.StatePending {
+			holdUnopened(b)
 			continue
 		}
 
-		key := int64(b.ID)
-		if key == 0 {
-			key = int64(idx + 1)
+		// The tracker is not saved; income is every paying seat, archive adds the expense
+		if b.Tracker == nil {
+			b.Tracker = he.NewTracker()
+			for _, slot := range b.Slots {
+				if slot.IsPlayer() {
+					b.Tracker.AddIncome(b.Cost)
+				}
+			}
 		}
 
-		BattleIndex[key] = &b
+		SetBattle(key, b)
 	}
 
 	return true, errR
@@ -1260,6 +1075,43 @@ func AddClientSeed(battle map[string]interface{}, key string, value interface{})
 	cs[key] = value
 }
 
+// pFairInt - Battle Helper
+// reads a number from PFair, which holds int in memory and float64 once loaded from JSON.
+func pFairInt(pFair map[string]interface{}, key string) (int, bool) {
+	switch v := pFair[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// battlePicker - Battle Helper
+// builds the item picker from what the battle recorded in PFair.
+func battlePicker(b *models.Battle) provablyfair.Picker {
+	picker := provablyfair.Picker{
+		Model: provablyfair.PickModelReRoll,
+		RNG:   battleRNG(b),
+	}
+	picker.ServerSeed, _ = b.PFair["serverSeed"].(string)
+	if v, ok := pFairInt(b.PFair, "pickModel"); ok {
+		picker.Model = v
+	}
+	picker.Curve.Name, _ = b.PFair["payoutCurve"].(string)
+	picker.Curve.Factor, _ = pFairInt(b.PFair, "curveFactor")
+	return picker
+}
+
+// battleRNG - Battle Helper
+// returns the RNG version of a battle; battles created before versioning used v1.
+func battleRNG(b *models.Battle) int {
+	if v, ok := pFairInt(b.PFair, "rng"); ok {
+		return v
+	}
+	return provablyfair.RNGSha256V1
+}
+
 // AddLog - Battle Helper
 func AddLog(b *models.Battle, action string, userID int64) {
 	b.Logs = append(b.Logs, models.BattleLog{
@@ -1269,6 +1121,121 @@ func AddLog(b *models.Battle, action string, userID int64) {
 	})
 }
 
+// moveBattle - Battle Helper
+// moves the battle through the state machine; a refused move comes back as ILLEGAL_STATE_TRANSITION.
+func moveBattle(b *models.Battle, to models.BattleState, status string) models.HandlerError {
+	var errR models.HandlerError
+	from := b.CurrentState()
+	if err := b.Move(to, status); err != nil {
+		log.Printf("[moveBattle] battle %d: %v", b.ID, err)
+		errR.Type = "ILLEGAL_STATE_TRANSITION"
+		errR.Code = 5011
+		errR.Data = map[string]interface{}{
+			"from": from,
+			"to":   to,
+		}
+	}
+	return errR
+}
+
+// seatStatus - Battle Helper
+// is where a waiting battle goes once its seats changed: rolling when full, else waiting.
+func seatStatus(emptyCount int) (models.BattleState, string) {
+	if emptyCount == 0 {
+		return models.StateRolling, "Start Rolling"
+	}
+	return models.StateWaiting, fmt.Sprintf(`Waiting for %d users`, emptyCount)
+}
+
+// discardBattle - Battle Helper
+// cancels a saved battle that was never opened and takes it off the live list, keeping the row for its log.
+func discardBattle(b *models.Battle, status string) {
+	if err := b.Move(models.StateCanceled, status); err != nil {
+		log.Printf("[discardBattle] battle %d: %v", b.ID, err)
+	}
+	AddLog(b, "discard", int64(b.CreatedBy))
+	if errR := Store.Update(b); errR.Code > 0 {
+		log.Printf("[discardBattle] battle %d: %s (%d)", b.ID, errR.Type, errR.Code)
+	}
+	if errR := Store.MarkArchived(b.ID); errR.Code > 0 {
+		log.Printf("[discardBattle] battle %d: %s (%d)", b.ID, errR.Type, errR.Code)
+	}
+}
+
+// markJoining - Battle Helper
+// saves a slot as being joined by a user, with the reference its entry fee is taken under.
+func markJoining(b *models.Battle, slotKey string, userID int, reference string) models.HandlerError {
+	if b.Joining == nil {
+		b.Joining = make(map[string]int)
+	}
+	if b.EntryRefs == nil {
+		b.EntryRefs = make(map[string]string)
+	}
+	b.Joining[slotKey] = userID
+	b.EntryRefs[slotKey] = reference
+	errR := Store.Update(b)
+	if errR.Code > 0 {
+		delete(b.Joining, slotKey)
+		delete(b.EntryRefs, slotKey)
+	}
+	return errR
+}
+
+// unmarkJoining - Battle Helper
+// forgets a join that did not seat its player; the caller saves the battle.
+func unmarkJoining(b *models.Battle, slotKey string) {
+	delete(b.Joining, slotKey)
+	delete(b.EntryRefs, slotKey)
+}
+
+// refundEntry - Battle Helper
+// rolls back an entry whose seat was not saved and records the refund in the battle log; the
+// seat's next entry gets a new reference. A refund UM refused is retried in the background and
+// logged again once it settles.
+func refundEntry(b *models.Battle, slotKey string, entry *wallet.Entry, reason string) {
+	nextEntryAttempt(b, slotKey, entry.UserID)
+	err := entry.Rollback(reason, func(err error) {
+		// The caller has let go of the battle by now
+		b.MU.Lock()
+		defer b.MU.Unlock()
+		logRefund(b, entry.UserID, err, "refundFailed")
+	})
+	logRefund(b, entry.UserID, err, "refundRetrying")
+}
+
+// logRefund - Battle Helper
+// adds a refund outcome to the battle log; failed is the action of a refund that did not go through.
+func logRefund(b *models.Battle, userID int, err error, failed string) {
+	action := "refund"
+	if err != nil {
+		action = failed
+	}
+	AddLog(b, action, int64(userID))
+
+	// Only a saved battle can keep the log
+	if b.ID > 0 {
+		if errR := Store.Update(b); errR.Code > 0 {
+			log.Printf("[refundEntry] battle %d: %s (%d)", b.ID, errR.Type, errR.Code)
+		}
+	}
+}
+
+// slotKeys - Battle Helper
+// returns the slot keys in order, so rolls and teams never depend on map order.
+func slotKeys(b *models.Battle) []string {
+	keys := make([]string, 0, len(b.Slots))
+	for key := range b.Slots {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
 // RemoveClientSeed - Battle Helper
 func RemoveClientSeed(battle map[string]interface{}, key string) {
 	cs, ok := battle["clientSeed"].(map[string]interface{})
@@ -1279,49 +1246,74 @@ func RemoveClientSeed(battle map[string]interface{}, key string) {
 }
 
 // ClientBattleIndex - Battle Helper
-func ClientBattleIndex(battles map[int64]*models.Battle) map[int64]models.BattleClient {
+// snapshots every live battle for clients, each under its own lock.
+func ClientBattleIndex() map[int64]models.BattleClient {
 	out := make(map[int64]models.BattleClient)
-	for _, b := range battles {
-		dto := models.BattleClient{
-			ID:             b.ID,
-			PlayerType:     b.PlayerType,
-			Options:        b.Options,
-			Cases:          b.Cases,
-			CasesUi:        b.CasesUi,
-			CaseCounts:     b.CaseCounts,
-			Cost:           b.Cost,
-			Slots:          b.Slots,
-			Status:         b.Status,
-			StatusCode:     b.StatusCode,
-			Summery:        b.Summery,
-			CreatedAt:      b.CreatedAt,
-			CreatedBy:      b.CreatedBy,
-			UpdatedAt:      b.UpdatedAt,
-			ServerSeedHash: b.PFair["serverSeedHash"].(string),
-		}
+	for _, b := range indexedBattles() {
+		b.MU.Lock()
+		dto := ClientBattle(b)
+		dto.CreatedBy = b.CreatedBy
+		b.MU.Unlock()
 		out[int64(b.ID)] = dto
 	}
 	return out
 }
 
 // ClientBattle - Battle Helper
+// copies what clients see of a battle; the caller holds b.MU for a live battle.
 func ClientBattle(b *models.Battle) models.BattleClient {
+	serverSeedHash, _ := b.PFair["serverSeedHash"].(string)
 	return models.BattleClient{
 		ID:             b.ID,
 		PlayerType:     b.PlayerType,
-		Options:        b.Options,
-		Cases:          b.Cases,
+		Options:        append([]string(nil), b.Options...),
+		Cases:          append([]int(nil), b.Cases...),
 		CasesUi:        b.CasesUi,
 		CaseCounts:     b.CaseCounts,
 		Cost:           b.Cost,
-		Slots:          b.Slots,
+		Slots:          cloneSlots(b.Slots),
 		Status:         b.Status,
 		StatusCode:     b.StatusCode,
-		Summery:        b.Summery,
+		Summery:        cloneSummery(b.Summery),
 		CreatedAt:      b.CreatedAt,
 		UpdatedAt:      b.UpdatedAt,
-		ServerSeedHash: b.PFair["serverSeedHash"].(string),
+		ServerSeedHash: serverSeedHash,
+	}
+}
+
+// cloneSlots - Battle Helper
+func cloneSlots(slots map[string]models.Slot) map[string]models.Slot {
+	out := make(map[string]models.Slot, len(slots))
+	for k, v := range slots {
+		out[k] = v
+	}
+	return out
+}
+
+// cloneSummery - Battle Helper
+// copies the maps and slices of a summery, so it can be encoded after the battle lock is released.
+func cloneSummery(sum models.Summery) models.Summery {
+	out := sum
+	if sum.Steps != nil {
+		out.Steps = make(map[int][]models.StepResult, len(sum.Steps))
+		for k, v := range sum.Steps {
+			out.Steps[k] = append([]models.StepResult(nil), v...)
+		}
+	}
+	if sum.Prizes != nil {
+		out.Prizes = make(map[string]float64, len(sum.Prizes))
+		for k, v := range sum.Prizes {
+			out.Prizes[k] = v
+		}
 	}
+	if sum.Jackpot != nil {
+		out.Jackpot = make(map[string]float64, len(sum.Jackpot))
+		for k, v := range sum.Jackpot {
+			out.Jackpot[k] = v
+		}
+	}
+	out.Winners.Slots = append([]string(nil), sum.Winners.Slots...)
+	return out
 }
 
 // expandCases - Battle Helper
@@ -1364,14 +1356,55 @@ func Roll(battleID int64, roundKey int) {
 		log.Println("Battle not found:", battleID)
 		return
 	}
+	rounds := len(battle.Cases)
+
+	switch rollNext(battle, roundKey) {
+	case rollSaved:
+		Roll(battleID, roundKey+1)
+	case rollAgain:
+		// Next round once clients revealed this one
+		scheduleRoll(battleID, roundKey+1, rounds, Pacing.RoundDelay)
+	case rollDone:
+		events.Emit("index", "heartbeat", ClientBattleIndex())
+		// Go to check Options
+		scheduleResolve(battleID)
+	}
+}
+
+// Outcomes of rollNext.
+const (
+	rollStop  = iota // refused or nothing to pay, the battle stays where it is
+	rollAgain        // a round was rolled, roll the next one after its reveal
+	rollSaved        // the round was saved before, roll the next one now
+	rollDone         // every round is rolled
+)
+
+// rollNext - Roll Helper
+// rolls one round under the battle lock, or moves the battle to rolled after the last one.
+func rollNext(battle *models.Battle, roundKey int) int {
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
 
 	// Normalize Teams
 	if roundKey == 0 {
-		HE, _ = he.GetAvgHE("g1_games", 30)
-		log.Printf("HE: %f", HE)
+		avgHE, _ := Store.AvgHE(30)
+		log.Printf("HE: %f", avgHE)
+
+		// Pin what replay tools need to reproduce every draw
+		if _, pinned := battle.PFair["pickModel"]; !pinned {
+			curve := provablyfair.CurveForHE(avgHE)
+			battle.PFair["he"] = avgHE
+			battle.PFair["nonceScheme"] = provablyfair.CurrentNonceScheme
+			battle.PFair["pickModel"] = provablyfair.PickModelCurve
+			battle.PFair["payoutCurve"] = curve.Name
+			battle.PFair["curveFactor"] = curve.Factor
+		}
+		if _, pinned := battle.PFair["cases"]; !pinned {
+			pinCases(battle)
+		}
 
-		time.Sleep(250 * time.Millisecond)
 		NormalizeTeams(battle)
+		UpdateBattle(battle)
 	}
 
 	// Check if roll has already done
@@ -1379,218 +1412,174 @@ func Roll(battleID int64, roundKey int) {
 		if configs.Debug {
 			log.Printf("Info: Round %d has already been rolled", roundKey)
 		}
-	} else {
-
-		// Count Last Roll Percentages
-		if roundKey > 0 {
-
-			var (
-				parts []float64
-				total float64
-			)
-
-			lastStep := battle.Summery.Steps[roundKey-1]
-			if lastStep != nil {
+		return rollSaved
+	}
 
-				for _, slot := range lastStep {
-					total += slot.Price
-					parts = append(parts, slot.Price)
-				}
-				result := utils.CalculatePercentages(parts, total)
+	// Count Last Roll Percentages
+	if roundKey > 0 {
+		countPercentages(battle, roundKey-1)
+	}
 
-				for j := range lastStep {
-					battle.Summery.Steps[roundKey-1][j].Percentage = result[j]
-				}
+	// Last Roll
+	if roundKey >= len(battle.Cases) {
+		if errR := moveBattle(battle, models.StateRolled, "Rolled"); errR.Code > 0 {
+			return rollStop
+		}
 
-			}
+		if !FillJackpot(battle) {
+			return rollStop
+		}
 
+		// Move to Option Level
+		if configs.Debug {
+			log.Printf("Battle %d steps(%d) are done.", battle.ID, roundKey)
 		}
+		UpdateBattle(battle)
+		return rollDone
+	}
 
-		// Last Roll
-		if roundKey >= len(battle.Cases) {
-			battle.Status = fmt.Sprintf("Rolled")
-			battle.StatusCode = 1
+	// Run Roll
+	if errR := moveBattle(battle, models.StateRolling, fmt.Sprintf("Roll %d", roundKey+1)); errR.Code > 0 {
+		return rollStop
+	}
+	emitRoundStarted(battle, roundKey)
+	RollRound(battle, roundKey)
+	AddLog(battle, fmt.Sprintf("Roll %d", roundKey+1), 0)
+	emitRoundResult(battle, roundKey)
 
-			if utils.InArray(battle.Options, "jackpot") {
-				// Ensure Jackpot map is initialized
-				if battle.Summery.Jackpot == nil {
-					battle.Summery.Jackpot = make(map[string]float64)
-				}
+	// Saved per round, so a restart resumes after the last saved one
+	UpdateBattle(battle)
+	return rollAgain
+}
 
-				// Calculate total
-				var total float64
-				for _, value := range battle.Summery.Prizes {
-					total += value
-				}
+// countPercentages - Roll Helper
+// shares of each slot in the total of a rolled round.
+func countPercentages(battle *models.Battle, roundKey int) {
+	var (
+		parts []float64
+		total float64
+	)
 
-				// Avoid division by zero
-				if total == 0 {
-					return
-				}
+	lastStep := battle.Summery.Steps[roundKey]
+	if lastStep == nil {
+		return
+	}
 
-				// Fill jackpot percentages
-				for key, value := range battle.Summery.Prizes {
-					battle.Summery.Jackpot[key] = utils.RoundToTwoDigits((value / total) * 100)
-				}
-			}
+	for _, slot := range lastStep {
+		total += slot.Price
+		parts = append(parts, slot.Price)
+	}
+	result := utils.CalculatePercentages(parts, total)
 
-			// Move to Option Level
-			if configs.Debug {
-				log.Printf("Battle %d steps(%d) are done.", battleID, roundKey)
-			}
-			UpdateBattle(battle)
-			events.Emit("all", "heartbeat", ClientBattleIndex(BattleIndex))
-			// Go to check Options
-			optionActions(battleID)
-			return
-		}
+	for j := range lastStep {
+		battle.Summery.Steps[roundKey][j].Percentage = result[j]
+	}
+}
 
-		// Run Roll
-		battle.Status = fmt.Sprintf("Roll %d", roundKey+1)
-		battle.StatusCode = 0
-		if battle.Summery.Steps == nil {
-			battle.Summery.Steps = make(map[int][]models.StepResult)
-		}
-		if battle.Summery.Prizes == nil {
-			battle.Summery.Prizes = make(map[string]float64)
-		}
-		nonce := ((roundKey + 7) * 2) + roundKey
-		caseID := battle.Cases[roundKey]
-		caseData := CasesImpacted[caseID]
-		var (
-			rollWinner string
-			lastPrize  float64
-		)
-		lastPrize = 0
-		for slot := range battle.Slots {
-			clientSeed, ok := battle.PFair["clientSeed"].(map[string]interface{})[slot].(string)
-			if !ok {
-				log.Println("No clientSeed for slot:", slot)
-				continue
-			}
+// FillJackpot - Roll Helper
+// fills the jackpot shares of a rolled battle; false means a jackpot battle with nothing won.
+func FillJackpot(battle *models.Battle) bool {
+	if !utils.InArray(battle.Options, "jackpot") {
+		return true
+	}
 
-			nonce += 97
-			item := provablyfair.PickItem(
-				HE,
-				caseData,
-				battle.PFair["serverSeed"].(string),
-				clientSeed,
-				nonce,
-			)
-			if configs.Debug {
-				log.Println("Roll "+strconv.Itoa(roundKey), slot, caseID, nonce, item["price"])
-			}
+	// Ensure Jackpot map is initialized
+	if battle.Summery.Jackpot == nil {
+		battle.Summery.Jackpot = make(map[string]float64)
+	}
 
-			if item == nil {
-
-				for {
-					nonce += 7
-					item = provablyfair.PickItem(
-						HE,
-						caseData,
-						battle.PFair["serverSeed"].(string),
-						clientSeed,
-						nonce,
-					)
-					if item != nil {
-						break
-					}
-				}
+	// Calculate total
+	var total float64
+	for _, value := range battle.Summery.Prizes {
+		total += value
+	}
 
-			}
+	// Avoid division by zero
+	if total == 0 {
+		return false
+	}
 
-			priceStr, _ := item["price"].(string)
-			price, _ := strconv.ParseFloat(priceStr, 64)
+	// Fill jackpot percentages
+	for key, value := range battle.Summery.Prizes {
+		battle.Summery.Jackpot[key] = utils.RoundToTwoDigits((value / total) * 100)
+	}
+	return true
+}
 
-			step := models.StepResult{
-				Slot:   slot,
-				ItemID: int(item["id"].(float64)),
-				Price:  utils.RoundToTwoDigits(price),
-			}
+// RollRound - Roll Helper
+// draws one round for every slot, including the last-round tie break; it has no side effects
+// beyond the battle itself, so tools can roll battles that are never stored.
+func RollRound(battle *models.Battle, roundKey int) {
+	if battle.Summery.Steps == nil {
+		battle.Summery.Steps = make(map[int][]models.StepResult)
+	}
+	if battle.Summery.Prizes == nil {
+		battle.Summery.Prizes = make(map[string]float64)
+	}
+	scheme := provablyfair.NonceSchemes[provablyfair.NonceSchemeV1]
+	picker := battlePicker(battle)
+	nonce := scheme.Round(roundKey)
+	caseID := battle.Cases[roundKey]
+	caseData := battleCase(battle, caseID)
+	var (
+		rollWinner string
+		lastPrize  float64
+	)
+	lastPrize = 0
+	for _, slot := range slotKeys(battle) {
+		clientSeed, ok := battle.PFair["clientSeed"].(map[string]interface{})[slot].(string)
+		if !ok {
+			log.Println("No clientSeed for slot:", slot)
+			continue
+		}
+
+		nonce = scheme.Slot(nonce)
+		step := drawStep(scheme, picker, caseData, clientSeed, slot, &nonce)
+		if configs.Debug {
+			log.Println("Roll "+strconv.Itoa(roundKey), slot, caseID, step.Nonce, step.Price)
+		}
 
-			// Rerun O	on Equality
-			if roundKey == len(battle.Cases)-1 {
+		// Rerun O	on Equality
+		if roundKey == len(battle.Cases)-1 {
 
-				reRoll := false
-				if utils.InArray(battle.Options, "madness") {
-					reRoll = isMinValue(battle.Summery.Prizes, battle.Summery.Prizes[slot])
+			// Only a slot leading into the last round breaks a tie with the leader
+			reRoll := false
+			madness := utils.InArray(battle.Options, "madness")
+			if total, rolled := battle.Summery.Prizes[slot]; rolled {
+				if madness {
+					reRoll = isMinValue(battle.Summery.Prizes, total)
 				} else {
-					reRoll = isMaxValue(battle.Summery.Prizes, battle.Summery.Prizes[slot])
+					reRoll = isMaxValue(battle.Summery.Prizes, total)
 				}
+			}
 
-				if reRoll {
-					for !isUniqueValue(battle.Summery.Prizes, battle.Summery.Prizes[slot]) {
-						nonce += 297
-						item = provablyfair.PickItem(
-							HE,
-							caseData,
-							battle.PFair["serverSeed"].(string),
-							clientSeed,
-							nonce,
-						)
-						if configs.Debug {
-							log.Println("Roll "+strconv.Itoa(roundKey), slot, caseID, nonce, item["price"])
-						}
-
-						if item == nil {
-
-							for {
-								nonce += 7
-								item = provablyfair.PickItem(
-									HE,
-									caseData,
-									battle.PFair["serverSeed"].(string),
-									clientSeed,
-									nonce,
-								)
-								if item != nil {
-									break
-								}
-							}
-
-						}
-
-						priceStr, _ = item["price"].(string)
-						price, _ = strconv.ParseFloat(priceStr, 64)
-
-						step = models.StepResult{
-							Slot:   slot,
-							ItemID: int(item["id"].(float64)),
-							Price:  utils.RoundToTwoDigits(price),
-						}
+			if reRoll {
+				for i := 0; i < maxReRolls && tiesLeader(battle.Summery.Prizes, slot, battle.Summery.Prizes[slot]+step.Price, madness); i++ {
+					attempts := rejectDraws(step, provablyfair.ReasonReRoll)
+					nonce = scheme.ReRoll(nonce)
+					step = drawStep(scheme, picker, caseData, clientSeed, slot, &nonce)
+					step.Attempts = append(attempts, step.Attempts...)
+					if configs.Debug {
+						log.Println("Roll "+strconv.Itoa(roundKey), slot, caseID, step.Nonce, step.Price)
 					}
 				}
-
 			}
 
-			battle.Summery.Steps[roundKey] = append(battle.Summery.Steps[roundKey], step)
-			battle.Summery.Prizes[slot] += step.Price
-			AddTeamPrizes(battle, slot, step.Price)
+		}
 
-			if lastPrize < step.Price {
-				rollWinner = slot
-			}
-			lastPrize = step.Price
+		battle.Summery.Steps[roundKey] = append(battle.Summery.Steps[roundKey], step)
+		battle.Summery.Prizes[slot] += step.Price
+		AddTeamPrizes(battle, slot, step.Price)
+
+		if lastPrize < step.Price {
+			rollWinner = slot
 		}
-		AddTeamRollWin(battle, rollWinner)
-		AddLog(battle, fmt.Sprintf("Roll %d", roundKey+1), 0)
+		lastPrize = step.Price
 	}
-	Roll(battleID, roundKey+1)
+	AddTeamRollWin(battle, rollWinner)
 }
 
 // check
-func isUniqueValue(m map[string]float64, target float64) bool {
-	count := 0
-	for _, v := range m {
-		if v == target {
-			count++
-			if count > 1 {
-				return false
-			}
-		}
-	}
-	return count == 1
-}
 func isMinValue(m map[string]float64, target float64) bool {
 	if len(m) == 0 {
 		return false
@@ -1616,6 +1605,63 @@ func isMaxValue(m map[string]float64, target float64) bool {
 	return true
 }
 
+// tiesLeader - Roll Helper
+// reports whether total ties the best of the other slots: the highest, or the lowest on madness.
+func tiesLeader(prizes map[string]float64, slot string, total float64, madness bool) bool {
+	var (
+		leader float64
+		found  bool
+	)
+	for k, v := range prizes {
+		if k == slot {
+			continue
+		}
+		if !found || (madness && v < leader) || (!madness && v > leader) {
+			leader, found = v, true
+		}
+	}
+	return found && utils.RoundToTwoDigits(total) == utils.RoundToTwoDigits(leader)
+}
+
+// drawStep - Roll Helper
+// picks an item for a slot; legacy re-roll battles step the round nonce past draws that match no item.
+func drawStep(scheme provablyfair.NonceScheme, picker provablyfair.Picker, c models.Case, clientSeed, slot string, nonce *int) models.StepResult {
+	var attempts []provablyfair.Draw
+	item, draws := picker.Pick(c, clientSeed, *nonce)
+	for i := 0; item == nil && picker.Model == provablyfair.PickModelReRoll && i < maxReplaySearch; i++ {
+		draws[len(draws)-1].Reason = provablyfair.ReasonMiss
+		attempts = append(attempts, draws...)
+		*nonce = scheme.Miss(*nonce)
+		item, draws = picker.Pick(c, clientSeed, *nonce)
+	}
+	final := draws[len(draws)-1]
+
+	step := models.StepResult{
+		Slot:     slot,
+		Nonce:    final.Nonce,
+		Roll:     final.Roll,
+		Attempts: attempts,
+	}
+	if item == nil {
+		log.Println("No item drawn for slot:", slot)
+		return step
+	}
+	step.ItemID = item.ID
+	step.Price = item.Price.Float()
+	return step
+}
+
+// rejectDraws - Roll Helper
+// returns all draws of a step with the winning one marked as rejected for reason.
+func rejectDraws(step models.StepResult, reason string) []provablyfair.Draw {
+	return append(step.Attempts, provablyfair.Draw{
+		Nonce:  step.Nonce,
+		Roll:   step.Roll,
+		ItemID: step.ItemID,
+		Reason: reason,
+	})
+}
+
 // optionActions - Battle Helper
 func optionActions(battleID int64) {
 	battle, ok := GetBattle(battleID)
@@ -1624,10 +1670,33 @@ func optionActions(battleID int64) {
 		return
 	}
 
-	// Wait for animations
-	time.Sleep(time.Duration(6*battle.CaseCounts) * time.Second)
+	battle.MU.Lock()
+
+	// Winners are only picked once the battle is allowed to resolve
+	if errR := moveBattle(battle, models.StateResolving, "Resolving"); errR.Code > 0 {
+		battle.MU.Unlock()
+		return
+	}
 
 	// Winner Team
+	ResolveWinner(battle)
+
+	AddLog(battle, "Handel Options", 0)
+	UpdateBattle(battle)
+	emitResolved(battle)
+	battle.MU.Unlock()
+
+	// Emit | heartbeat
+	events.Emit("index", "heartbeat", ClientBattleIndex())
+
+	// Archive battle
+	archive(battle.ID)
+	return
+}
+
+// ResolveWinner - Battle Helper
+// picks the winning team by the battle options and splits the total prize between its slots.
+func ResolveWinner(battle *models.Battle) {
 	winner := battle.Teams[0]
 	if len(battle.Options) == 0 {
 		// No Options
@@ -1680,19 +1749,6 @@ func optionActions(battleID int64) {
 	battle.Summery.Winners = winner
 	battle.Summery.Winners.TotalPrizes = utils.RoundToTwoDigits(total)
 	battle.Summery.Winners.SlotPrizes = utils.RoundToTwoDigits(total / float64(len(battle.Summery.Winners.Slots)))
-
-	battle.Status = "Resolving"
-	battle.StatusCode = 2
-
-	AddLog(battle, "Handel Options", 0)
-	UpdateBattle(battle)
-
-	// Emit | heartbeat
-	events.Emit("all", "heartbeat", ClientBattleIndex(BattleIndex))
-
-	// Archive battle
-	archive(battle.ID)
-	return
 }
 
 // archive - Battle Helper
@@ -1708,55 +1764,86 @@ func archive(battleID int) (models.HandlerOK, models.HandlerError) {
 		return resR, models.HandlerError{}
 	}
 
-	for _, v := range battle.Summery.Winners.Slots {
-		userID := battle.Slots[v].ID
+	if errR = payWinners(battle); errR.Code > 0 {
+		return resR, errR
+	}
 
-		// Skip Empty / Bot
-		if battle.Slots[v].Type != "Player" {
+	if errR = Store.MarkArchived(battle.ID); errR.Code > 0 {
+		return resR, errR
+	}
+
+	// Emit | heartbeat
+	events.Emit("index", "heartbeat", ClientBattleIndex())
+
+	// HE Tracks
+	battle.MU.Lock()
+	Store.SaveHE(battle.ID, battle.Tracker)
+	battle.MU.Unlock()
+
+	// Keep on Index
+	scheduleRetire(battle.ID)
+
+	return resR, models.HandlerError{}
+}
+
+// retire - Battle Helper
+// archives a rewarded battle once its retention is over and drops it from the index.
+func retire(battleID int) {
+	battle, ok := GetBattle(int64(battleID))
+	if !ok {
+		return
+	}
+
+	battle.MU.Lock()
+	if errR := moveBattle(battle, models.StateArchived, "Archived"); errR.Code > 0 {
+		battle.MU.Unlock()
+		return
+	}
+	UpdateBattle(battle)
+	battle.MU.Unlock()
+
+	// Drop Battle from Index
+	dropBattle(battle.ID)
+
+	// Emit | heartbeat
+	events.Emit("index", "heartbeat", ClientBattleIndex())
+}
+
+// payWinners - Battle Helper
+// pays every winning player under the battle lock and moves the battle to rewarding.
+func payWinners(battle *models.Battle) models.HandlerError {
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
+	// Only a resolved battle is paid; one that can not move on would be paid again on every retry
+	if !battle.CanMove(models.StateRewarding) {
+		return moveBattle(battle, models.StateRewarding, "Rewarding")
+	}
+
+	// Payouts - recorded before paying, so a failed one is left for the reconciler
+	recordPayouts(battle)
+	UpdateBattle(battle)
+
+	for _, v := range battle.Summery.Winners.Slots {
+		payout, ok := battle.Payouts[v]
+		if !ok {
 			continue
 		}
 
-		// Get Users
-		resp, err := utils.GetUser(userID)
-		if err != nil {
-			return resR, models.HandlerError{}
-		}
-		errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-		if status != 1 {
-			errR.Type = errType
-			errR.Code = errCode
-			if resp["data"] != nil {
-				errR.Data = resp["data"]
-			}
-			return resR, errR
-		}
+		// HE Tracks - owed whether it is paid now or by a retry
+		battle.Tracker.AddExpense(payout.Amount)
 
-		// Add Transaction
-		Transaction, err := utils.AddTransaction(
-			userID,
-			"game_win",
-			strconv.Itoa(battleID),
-			battle.Summery.Winners.SlotPrizes,
-			"",
-			"Case Battle",
-		)
-		if err != nil {
-			return resR, models.HandlerError{}
+		// Paid before a restart
+		if payout.State == models.PayoutPaid {
+			continue
 		}
-		errCode, status, errType = utils.SafeExtractErrorStatus(Transaction)
-		if status != 1 {
-			errR.Type = errType
-			errR.Code = errCode
-			if resp["data"] != nil {
-				errR.Data = resp["data"]
-			}
-			return resR, errR
+		if !payOut(battle, v) {
+			UpdateBattle(battle)
+			continue
 		}
 
-		// HE Tracks
-		battle.Tracker.AddExpense(battle.Summery.Winners.SlotPrizes)
-
 		// Send Live Winner
+		displayName, bet, amount := battle.Slots[v].DisplayName, battle.Cost, payout.Amount
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
@@ -1764,10 +1851,10 @@ func archive(battleID int) (models.HandlerOK, models.HandlerError) {
 				}
 			}()
 			ok := sendLiveWinner(
-				battle.Slots[v].DisplayName,
-				fmt.Sprintf("%.2f", battle.Cost),
+				displayName,
+				fmt.Sprintf("%.2f", bet),
 				"",
-				fmt.Sprintf("%.2f", battle.Summery.Winners.SlotPrizes),
+				fmt.Sprintf("%.2f", amount),
 			)
 			if ok == false {
 				log.Printf("sendLiveWinner error")
@@ -1777,63 +1864,16 @@ func archive(battleID int) (models.HandlerOK, models.HandlerError) {
 		UpdateBattle(battle)
 	}
 
-	battle.Status = "Rewarding"
-	battle.StatusCode = 3
-	UpdateBattle(battle)
-
-	// Sanitize and build query
-	query := fmt.Sprintf(
-		`Update g1_games SET is_live = 0 WHERE id = %d`,
-		battle.ID,
-	)
-
-	// gRPC Call
-	res, err := grpcclient.SendQuery(query)
-	if err != nil || res == nil || res.Status != "ok" {
-		errR.Type = "PROFILE_GRPC_ERROR"
-		errR.Code = 1033
-		if res != nil {
-			errR.Data = res.Error
-		}
-		return resR, errR
+	if errR := moveBattle(battle, models.StateRewarding, "Rewarding"); errR.Code > 0 {
+		return errR
 	}
-
-	// Extract gRPC struct
-	dataDB := res.Data.GetFields()
-
-	// DB result rows count
-	exist := dataDB["rows_affected"].GetNumberValue()
-	if exist == 0 {
-		errR.Type = "USER_NOT_FOUND"
-		errR.Code = 1035
-		return resR, errR
-	}
-
-	// Emit | heartbeat
-	events.Emit("all", "heartbeat", ClientBattleIndex(BattleIndex))
-
-	// HE Tracks
-	battle.Tracker.Save("g1_games", battle.ID)
-
-	// Keep on Index
-	time.Sleep(600 * time.Second)
-
-	battle.Status = "Archived"
-	battle.StatusCode = -1
 	UpdateBattle(battle)
-
-	// Drop Battle from Index
-	go dropBattle(battle.ID, 0)
-
-	// Emit | heartbeat
-	events.Emit("all", "heartbeat", ClientBattleIndex(BattleIndex))
-
-	return resR, models.HandlerError{}
+	return models.HandlerError{}
 }
 
 // dropBattle - Battle Helper
-func dropBattle(battleId int, after int) {
-	time.Sleep(time.Duration(after) * time.Second)
+// takes a battle off the live index; it takes the battle lock, so callers holding it run it in a goroutine.
+func dropBattle(battleId int) {
 	battle, ok := GetBattle(int64(battleId))
 	if ok {
 		DeleteBattle(int64(battle.ID))
@@ -1907,7 +1947,7 @@ func NormalizeTeams(b *models.Battle) {
 	switch b.PlayerType {
 	case "1v1", "1v1v1", "1v1v1v1", "1v6":
 		var i = 0
-		for key := range b.Slots {
+		for _, key := range slotKeys(b) {
 			b.Teams = append(b.Teams, models.Team{
 				Slots: []string{key},
 			})
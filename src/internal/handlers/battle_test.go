@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/standin"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/store"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/wallet"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupHandlers points the handlers at an empty in-memory store and index and a stand-in UM
+// with the fixture cases and bots, and returns the UM.
+func setupHandlers(t *testing.T) *standin.UM {
+	t.Helper()
+
+	um := standin.NewUM("test-app", "test-x")
+	srv := httptest.NewServer(um)
+	t.Cleanup(srv.Close)
+	umclient.Setup(umclient.Config{
+		BaseURL:         srv.URL,
+		AppToken:        "test-app",
+		XKey:            "test-x",
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    5 * time.Second,
+		BreakerFailures: 100,
+		BreakerCooldown: time.Second,
+	})
+	auth.Setup(auth.DefaultConfig())
+
+	Store = store.NewMemory()
+	battleIndexMu.Lock()
+	BattleIndex = make(map[int64]*models.Battle)
+	battleIndexMu.Unlock()
+	if _, err := LoadCasesFixture("../../configs/fixtures/cases.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBotsFixture("../../configs/fixtures/bots.json"); err != nil {
+		t.Fatal(err)
+	}
+	return um
+}
+
+func TestJoinSameSlotConcurrently(t *testing.T) {
+	um := setupHandlers(t)
+
+	const joiners = 16
+	um.AddUser("token-creator", 1, "Creator", 100)
+	for i := 1; i <= joiners; i++ {
+		um.AddUser(fmt.Sprintf("token-%d", i), int64(100+i), fmt.Sprintf("Joiner %d", i), 100)
+	}
+
+	// 2v2, so the battle keeps waiting with the contested seat taken
+	created, errR := NewBattle(map[string]interface{}{
+		"token":      "token-creator",
+		"playerType": "2v2",
+		"cases":      []interface{}{map[string]interface{}{"1": float64(1)}},
+	})
+	if errR.Code > 0 {
+		t.Fatalf("newBattle: %s (%d)", errR.Type, errR.Code)
+	}
+	battleID := created.Data.(models.BattleCreated).ID
+
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		mu      sync.Mutex
+		joined  []int
+		refused = make(map[string]int)
+	)
+	for i := 1; i <= joiners; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errR := Join(map[string]interface{}{
+				"token":    fmt.Sprintf("token-%d", i),
+				"battleId": float64(battleID),
+				"slotId":   float64(2),
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if errR.Code > 0 {
+				refused[errR.Type]++
+				return
+			}
+			joined = append(joined, 100+i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(joined) != 1 {
+		t.Fatalf("%d joins went through, want 1 (refused: %v)", len(joined), refused)
+	}
+	if refused["SLOT_IS_NOT_EMPTY"] != joiners-1 {
+		t.Errorf("refused %v, want %d SLOT_IS_NOT_EMPTY", refused, joiners-1)
+	}
+
+	battle, ok := GetBattle(int64(battleID))
+	if !ok {
+		t.Fatalf("battle %d not on the index", battleID)
+	}
+	battle.MU.Lock()
+	seat := battle.Slots["s2"]
+	players := append([]int(nil), battle.Players...)
+	battle.MU.Unlock()
+	if !seat.IsPlayer() || seat.ID != joined[0] {
+		t.Errorf("s2 is %+v, want user %d", seat, joined[0])
+	}
+	if len(players) != 2 {
+		t.Errorf("players %v, want the creator and user %d", players, joined[0])
+	}
+
+	debits := make(map[int]int)
+	for _, tx := range um.Transactions() {
+		if tx.Type == "game_loss" {
+			debits[tx.UserID]++
+		}
+	}
+	for i := 1; i <= joiners; i++ {
+		id := 100 + i
+		want := 0
+		if id == joined[0] {
+			want = 1
+		}
+		if debits[id] != want {
+			t.Errorf("user %d was debited %d times, want %d", id, debits[id], want)
+		}
+	}
+}
+
+func TestRecoverRefundsUnsavedJoin(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-creator", 1, "Creator", 100)
+	um.AddUser("token-joiner", 2, "Joiner", 100)
+
+	created, errR := NewBattle(map[string]interface{}{
+		"token":      "token-creator",
+		"playerType": "1v1",
+		"cases":      []interface{}{map[string]interface{}{"1": float64(1)}},
+	})
+	if errR.Code > 0 {
+		t.Fatalf("newBattle: %s (%d)", errR.Type, errR.Code)
+	}
+	battleID := created.Data.(models.BattleCreated).ID
+	battle, _ := GetBattle(int64(battleID))
+
+	// Join dies after the debit, before the seat is saved
+	battle.MU.Lock()
+	errR = markJoining(battle, "s2", 2, entryReference(battle, "s2", 2))
+	reference := battle.EntryRefs["s2"]
+	battle.MU.Unlock()
+	if errR.Code > 0 {
+		t.Fatalf("markJoining: %s (%d)", errR.Type, errR.Code)
+	}
+	if entry, errR := wallet.Reserve(2, battle.Cost, reference, "Case Battle"); entry == nil {
+		t.Fatalf("reserve: %s (%d)", errR.Type, errR.Code)
+	}
+
+	// Restart
+	battleIndexMu.Lock()
+	BattleIndex = make(map[int64]*models.Battle)
+	battleIndexMu.Unlock()
+	if _, errR := FillBattleIndex(); errR.Code > 0 {
+		t.Fatalf("fillBattleIndex: %s (%d)", errR.Type, errR.Code)
+	}
+	RecoverBattles()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for um.Balance(2) != 100 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if um.Balance(2) != 100 {
+		t.Fatalf("joiner has %.2f after recovery, want 100", um.Balance(2))
+	}
+	recovered, _ := GetBattle(int64(battleID))
+	recovered.MU.Lock() // settled once the lock is free
+	recovered.MU.Unlock()
+
+	var debits, refunds int
+	for _, tx := range um.Transactions() {
+		switch {
+		case tx.UserID == 2 && tx.Type == "game_loss" && tx.ReferenceID == reference:
+			debits++
+		case tx.UserID == 2 && tx.Type == "game_win" && tx.ReferenceID == wallet.RefundReference(reference):
+			refunds++
+		}
+	}
+	if debits != 1 || refunds != 1 {
+		t.Errorf("%d debits and %d refunds under %s, want 1 and 1", debits, refunds, reference)
+	}
+
+	stored, errR := Store.Load(battleID)
+	if errR.Code > 0 {
+		t.Fatalf("load: %s (%d)", errR.Type, errR.Code)
+	}
+	if len(stored.Joining) > 0 || stored.Slots["s2"].Type != "Empty" {
+		t.Errorf("s2 after recovery: joining %v, slot %+v", stored.Joining, stored.Slots["s2"])
+	}
+}
+
+func TestChangeSeat(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-creator", 1, "Creator", 100)
+	um.AddUser("token-stranger", 2, "Stranger", 100)
+
+	created, errR := NewBattle(map[string]interface{}{
+		"token":      "token-creator",
+		"playerType": "2v2",
+		"cases":      []interface{}{map[string]interface{}{"1": float64(1)}},
+	})
+	if errR.Code > 0 {
+		t.Fatalf("newBattle: %s (%d)", errR.Type, errR.Code)
+	}
+	battleID := created.Data.(models.BattleCreated).ID
+
+	tests := []struct {
+		name    string
+		token   string
+		slotID  int
+		errType string
+		seated  string
+	}{
+		{"not joined", "token-stranger", 3, "NOT_JOINED", "s1"},
+		{"taken slot", "token-creator", 1, "SLOT_IS_NOT_EMPTY", "s1"},
+		{"empty slot", "token-creator", 3, "", "s3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errR := ChangeSeat(map[string]interface{}{
+				"token":    tt.token,
+				"battleId": float64(battleID),
+				"slotId":   float64(tt.slotID),
+			})
+			if errR.Type != tt.errType {
+				t.Errorf("changeSeat: %q, want %q", errR.Type, tt.errType)
+			}
+
+			battle, _ := GetBattle(int64(battleID))
+			battle.MU.Lock()
+			defer battle.MU.Unlock()
+			if _, phantom := battle.Slots[""]; phantom || len(battle.Slots) != 4 {
+				t.Errorf("slots %v", battle.Slots)
+			}
+			if seat := battle.Slots[tt.seated]; !seat.IsPlayer() || seat.ID != 1 {
+				t.Errorf("creator not on %s: %+v", tt.seated, seat)
+			}
+			if _, ok := battle.EntryRefs[tt.seated]; !ok || len(battle.EntryRefs) != 1 {
+				t.Errorf("entry refs %v, want one on %s", battle.EntryRefs, tt.seated)
+			}
+		})
+	}
+}
+
+func TestOptionActionsOutOfTurn(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-creator", 1, "Creator", 100)
+
+	created, errR := NewBattle(map[string]interface{}{
+		"token":      "token-creator",
+		"playerType": "1v1",
+		"cases":      []interface{}{map[string]interface{}{"1": float64(1)}},
+	})
+	if errR.Code > 0 {
+		t.Fatalf("newBattle: %s (%d)", errR.Type, errR.Code)
+	}
+	battleID := created.Data.(models.BattleCreated).ID
+	b, _ := GetBattle(int64(battleID))
+	b.MU.Lock()
+	b.Teams = []models.Team{{Slots: []string{"s1"}, TotalPrizes: 5}, {Slots: []string{"s2"}, TotalPrizes: 9}}
+	b.MU.Unlock()
+
+	// Still waiting: resolving is refused and no winner is picked
+	optionActions(int64(battleID))
+
+	b.MU.Lock()
+	defer b.MU.Unlock()
+	if state := b.CurrentState(); state != models.StateWaiting {
+		t.Errorf("state %s, want %s", state, models.StateWaiting)
+	}
+	if len(b.Summery.Winners.Slots) > 0 || b.Summery.Winners.SlotPrizes != 0 {
+		t.Errorf("winners picked before resolving: %+v", b.Summery.Winners)
+	}
+}
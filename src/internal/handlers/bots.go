@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
 	"google.golang.org/protobuf/types/known/structpb"
 	"log"
 	"math/rand/v2"
+	"os"
 )
 
 var (
@@ -63,22 +67,11 @@ func AddBot(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
+	userID := int(profile.ID)
 
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
@@ -92,7 +85,11 @@ func AddBot(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 		return resR, errR
 	}
 
-	if battle.StatusCode > 0 {
+	// Lock Battle
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -156,23 +153,17 @@ func AddBot(data map[string]interface{}) (models.HandlerOK, models.HandlerError)
 			emptyCount++
 		}
 	}
+	state, statusText := seatStatus(emptyCount)
+	if errR = moveBattle(battle, state, statusText); errR.Code > 0 {
+		return resR, errR
+	}
+	var update, errV = UpdateBattle(battle)
+	if update != true {
+		return resR, errV
+	}
 	if emptyCount == 0 {
 		// Force To Roll
-		battle.Status = "Start Rolling"
-		battle.StatusCode = 0
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
-		go func(bid int) {
-			Roll(int64(battle.ID), 0)
-		}(battle.ID)
-	} else {
-		battle.Status = fmt.Sprintf(`Waiting for %d users`, emptyCount)
-		var update, errV = UpdateBattle(battle)
-		if update != true {
-			return resR, errV
-		}
+		scheduleRoll(int64(battle.ID), 0, len(battle.Cases), Pacing.StartDelay)
 	}
 
 	// Success
@@ -200,22 +191,11 @@ func AddBotAll(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
+	userID := int(profile.ID)
 
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
@@ -229,7 +209,11 @@ func AddBotAll(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		return resR, errR
 	}
 
-	if battle.StatusCode > 0 {
+	// Lock Battle
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -285,15 +269,14 @@ func AddBotAll(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 	}
 
 	// Force To Roll
-	battle.Status = "Start Rolling"
-	battle.StatusCode = 0
+	if errR = moveBattle(battle, models.StateRolling, "Start Rolling"); errR.Code > 0 {
+		return resR, errR
+	}
 	var update, errV = UpdateBattle(battle)
 	if update != true {
 		return resR, errV
 	}
-	go func(bid int) {
-		Roll(int64(battle.ID), 0)
-	}(battle.ID)
+	scheduleRoll(int64(battle.ID), 0, len(battle.Cases), Pacing.StartDelay)
 	// Success
 	resR.Type = "addBotAll"
 	resR.Data = botAdded
@@ -312,22 +295,11 @@ func ClearSlot(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 	if !ok {
 		return resR, vErr
 	}
-	resp, err := utils.VerifyJWT(userJWT)
+	profile, err := auth.VerifyJWT(userJWT)
 	if err != nil {
-		return resR, models.HandlerError{}
-	}
-	errCode, status, errType := utils.SafeExtractErrorStatus(resp)
-	if status != 1 {
-		errR.Type = errType
-		errR.Code = errCode
-		if resp["data"] != nil {
-			errR.Data = resp["data"]
-		}
-		return resR, errR
+		return resR, umclient.AsHandlerError(err)
 	}
-	userData := resp["data"].(map[string]interface{})
-	profile := userData["profile"].(map[string]interface{})
-	userID := int(profile["id"].(float64))
+	userID := int(profile.ID)
 
 	// Get Battle
 	battleId, vErr, ok := validate.RequireInt(data, "battleId")
@@ -341,7 +313,11 @@ func ClearSlot(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 		return resR, errR
 	}
 
-	if battle.StatusCode > 0 {
+	// Lock Battle
+	battle.MU.Lock()
+	defer battle.MU.Unlock()
+
+	if battle.CurrentState() != models.StateWaiting {
 		errR.Type = "GAME_IS_LOCKED"
 		errR.Code = 5007
 		return resR, errR
@@ -392,14 +368,9 @@ func ClearSlot(data map[string]interface{}) (models.HandlerOK, models.HandlerErr
 			emptyCount++
 		}
 	}
-	if emptyCount == 0 {
-		// Force To Roll
-		battle.Status = "Start Rolling"
-		battle.StatusCode = 0
-		Roll(int64(battle.ID), 0)
-	} else {
-		battle.Status = fmt.Sprintf(`Waiting for %d users`, emptyCount)
-		battle.StatusCode = 0
+	state, statusText := seatStatus(emptyCount)
+	if errR = moveBattle(battle, state, statusText); errR.Code > 0 {
+		return resR, errR
 	}
 	var update, errV = UpdateBattle(battle)
 	if update != true {
@@ -420,8 +391,16 @@ func FillBots() (*structpb.ListValue, models.HandlerError) {
 	var (
 		errR models.HandlerError
 	)
+	if path := os.Getenv("BOTS_FIXTURE"); path != "" {
+		if _, err := LoadBotsFixture(path); err != nil {
+			log.Println("Failed to load bots fixture:", err)
+			errR.Type = "DB_DATA"
+			errR.Code = 1070
+		}
+		return DbBots, errR
+	}
 	// Sanitize and build query
-	query := fmt.Sprintf(`SELECT * FROM bots`)
+	query := `SELECT * FROM bots`
 	// gRPC Call
 	res, err := grpcclient.SendQuery(query)
 	if err != nil || res == nil || res.Status != "ok" {
@@ -447,6 +426,25 @@ func FillBots() (*structpb.ListValue, models.HandlerError) {
 	return DbBots, errR
 }
 
+// LoadBotsFixture - Helper
+// fills DbBots from a JSON array of bots rows, for running without Core.
+func LoadBotsFixture(path string) (*structpb.ListValue, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return DbBots, err
+	}
+	var rows []interface{}
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return DbBots, err
+	}
+	bots, err := structpb.NewList(rows)
+	if err != nil {
+		return DbBots, err
+	}
+	DbBots = bots
+	return DbBots, nil
+}
+
 // randomBot - Helper
 func randomBot(DbBots *structpb.ListValue) *structpb.Value {
 	if DbBots == nil || len(DbBots.Values) == 0 {
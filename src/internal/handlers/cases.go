@@ -1,17 +1,26 @@
 package handlers
 
 import (
-	"fmt"
+	"encoding/json"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
 	"google.golang.org/protobuf/types/known/structpb"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 )
 
 var (
 	DbCases       *structpb.ListValue
 	DbCaseItems   *structpb.ListValue
-	CasesImpacted map[int]grpcclient.CaseWithItems
+	CasesImpacted map[int]models.Case
+	// CasesRefused - cases kept out of CasesImpacted by auditCases, still needed to roll and verify older battles
+	CasesRefused map[int]models.Case
+	CaseIssues   map[int][]provablyfair.RangeIssue
 )
 
 // GetCases - Handler
@@ -46,14 +55,70 @@ func UpdateCases(_ map[string]interface{}) (models.HandlerOK, models.HandlerErro
 	return resR, errR
 }
 
+// GetCaseOdds - Handler
+// publishes the probability table of every case under each payout curve, plus the policy choosing them.
+func GetCaseOdds(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if len(CasesImpacted) == 0 {
+		FillCaseImpact()
+	}
+
+	caseFilter := 0
+	if _, exists := data["caseId"]; exists {
+		caseID, vErr, ok := validate.RequireInt(data, "caseId")
+		if !ok {
+			return resR, vErr
+		}
+		if _, found := CasesImpacted[int(caseID)]; !found {
+			errR.Type = "INVALID_CASE_ID"
+			errR.Code = 1027
+			return resR, errR
+		}
+		caseFilter = int(caseID)
+	}
+
+	curves := provablyfair.Curves()
+	tables := make(map[int]map[string]provablyfair.OddsTable)
+	for caseID, c := range CasesImpacted {
+		if caseFilter > 0 && caseID != caseFilter {
+			continue
+		}
+		tables[caseID] = make(map[string]provablyfair.OddsTable, len(curves))
+		for name, curve := range curves {
+			tables[caseID][name] = provablyfair.BuildOddsTable(c, curve)
+		}
+	}
+
+	// Success
+	resR.Type = "getCaseOdds"
+	resR.Data = map[string]interface{}{
+		"policy":         provablyfair.PayoutPolicy,
+		"minCurveFactor": provablyfair.MinCurveFactor,
+		"cases":          tables,
+	}
+	return resR, errR
+}
+
 // FillCaseImpact - Helper
-func FillCaseImpact() (map[int]grpcclient.CaseWithItems, models.HandlerError) {
+func FillCaseImpact() (map[int]models.Case, models.HandlerError) {
 	log.Println("Fill CasesImpacted...")
 	var (
 		errR models.HandlerError
 	)
+	if path := os.Getenv("CASES_FIXTURE"); path != "" {
+		if _, err := LoadCasesFixture(path); err != nil {
+			log.Println("Failed to load cases fixture:", err)
+			errR.Type = "DB_DATA"
+			errR.Code = 1070
+		}
+		return CasesImpacted, errR
+	}
 	// Sanitize and build query
-	query := fmt.Sprintf(`SELECT id,name,color,price,distribution,rarity,weight FROM cases WHERE publish_status=1`)
+	query := `SELECT id,name,color,price,distribution,rarity,weight FROM cases WHERE publish_status=1`
 	// gRPC Call
 	res, err := grpcclient.SendQuery(query)
 	if err != nil || res == nil || res.Status != "ok" {
@@ -77,7 +142,7 @@ func FillCaseImpact() (map[int]grpcclient.CaseWithItems, models.HandlerError) {
 	DbCases = dataDB["rows"].GetListValue()
 
 	// Sanitize and build query
-	query = fmt.Sprintf(`SELECT 
+	query = `SELECT 
     	ci.id,
     	ci.case_id,
     	ci.item_id,
@@ -88,7 +153,7 @@ func FillCaseImpact() (map[int]grpcclient.CaseWithItems, models.HandlerError) {
    		ci.color,
    		ir.market_hash_name,
    		ir.category,
-   		ir.wear FROM case_items ci LEFT JOIN g1_items ir ON ci.item_id = ir.item_orginal_id`)
+   		ir.wear FROM case_items ci LEFT JOIN g1_items ir ON ci.item_id = ir.item_orginal_id`
 	// gRPC Call
 	res, err = grpcclient.SendQuery(query)
 	if err != nil || res == nil || res.Status != "ok" {
@@ -112,7 +177,133 @@ func FillCaseImpact() (map[int]grpcclient.CaseWithItems, models.HandlerError) {
 	DbCaseItems = dataDB["rows"].GetListValue()
 
 	// Merge Data
-	CasesImpacted = grpcclient.MergeCasesAndItems(grpcclient.ListValueToStructs(DbCases), grpcclient.ListValueToStructs(DbCaseItems))
+	auditCases(grpcclient.MergeCasesAndItems(grpcclient.ListValueToStructs(DbCases), grpcclient.ListValueToStructs(DbCaseItems)))
 
 	return CasesImpacted, errR
 }
+
+// LoadCasesFixture - Helper
+// fills CasesImpacted from a JSON file in the getCases response shape, for tools running without Core.
+func LoadCasesFixture(path string) (map[int]models.Case, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return CasesImpacted, err
+	}
+	var cases map[int]models.Case
+	if err := json.Unmarshal(raw, &cases); err != nil {
+		return CasesImpacted, err
+	}
+	for id, c := range cases {
+		c.SortItems()
+		cases[id] = c
+	}
+
+	auditCases(cases)
+	return CasesImpacted, nil
+}
+
+// GetCaseAudit - Handler
+// lists the cases refused for broken min_rand/max_rand ranges and what is wrong with them.
+func GetCaseAudit(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Admin Key
+	_, err := utils.ValidateAdminKey(data)
+	if err != nil {
+		errParts := strings.Split(err.Error(), ":")
+		errR.Type = errParts[0]
+		errR.Code, _ = strconv.Atoi(errParts[1])
+		return resR, errR
+	}
+
+	if len(CasesImpacted) == 0 && len(CasesRefused) == 0 {
+		FillCaseImpact()
+	}
+
+	// Success
+	resR.Type = "getCaseAudit"
+	resR.Data = map[string]interface{}{
+		"published": len(CasesImpacted),
+		"refused":   len(CasesRefused),
+		"issues":    CaseIssues,
+	}
+	return resR, errR
+}
+
+// auditCases - Helper
+// publishes the cases whose ranges map every roll to exactly one item and keeps the rest aside.
+func auditCases(cases map[int]models.Case) {
+	published := make(map[int]models.Case, len(cases))
+	refused := make(map[int]models.Case)
+	issues := make(map[int][]provablyfair.RangeIssue)
+	for caseID, c := range cases {
+		found := provablyfair.CheckRanges(c)
+		if len(found) == 0 {
+			published[caseID] = c
+			continue
+		}
+		refused[caseID] = c
+		issues[caseID] = found
+		log.Printf("Case %d refused: %d range issues, first: %+v", caseID, len(found), found[0])
+	}
+	CasesImpacted = published
+	CasesRefused = refused
+	CaseIssues = issues
+}
+
+// lookupCase - Helper
+// returns a case to roll or replay, including refused cases older battles were created with.
+func lookupCase(caseID int) models.Case {
+	if c, ok := CasesImpacted[caseID]; ok {
+		return c
+	}
+	return CasesRefused[caseID]
+}
+
+// pinCases - Helper
+// snapshots the table of every case of a battle into PFair, so later case edits cannot change
+// how its rounds roll or replay.
+func pinCases(b *models.Battle) {
+	tables := make(map[string]models.Case, len(b.Cases))
+	for _, caseID := range b.Cases {
+		tables[strconv.Itoa(caseID)] = lookupCase(caseID)
+	}
+	b.PFair["cases"] = tables
+}
+
+// pinnedCases - Helper
+// returns the case tables pinned in PFair; battles loaded from the store carry them as decoded JSON.
+func pinnedCases(b *models.Battle) (map[string]models.Case, bool) {
+	switch v := b.PFair["cases"].(type) {
+	case nil:
+		return nil, false
+	case map[string]models.Case:
+		return v, true
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, false
+		}
+		var tables map[string]models.Case
+		if err := json.Unmarshal(raw, &tables); err != nil {
+			log.Printf("[pinnedCases] battle %d: %v", b.ID, err)
+			return nil, false
+		}
+		return tables, true
+	}
+}
+
+// battleCase - Helper
+// returns a case as the battle rolls it: the table pinned when rolling started, or the current
+// one for battles that started rolling before tables were pinned.
+func battleCase(b *models.Battle, caseID int) models.Case {
+	if tables, ok := pinnedCases(b); ok {
+		if c, found := tables[strconv.Itoa(caseID)]; found {
+			return c
+		}
+	}
+	return lookupCase(caseID)
+}
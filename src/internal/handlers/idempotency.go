@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"log"
+	"regexp"
+	"sync"
+)
+
+var (
+	idempotencyKeyRe = regexp.MustCompile(`^[A-Za-z0-9_:.-]{8,64}$`)
+
+	// inFlight - keys of requests still running, so a concurrent duplicate waits for the first
+	inFlight   = make(map[string]*keyLock)
+	inFlightMu sync.Mutex
+)
+
+// keyLock - a key's lock and the number of requests holding or waiting for it
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// optionalIdempotencyKey - Helper
+// reads the optional "idempotencyKey" of a money-moving request.
+func optionalIdempotencyKey(data map[string]interface{}) (string, models.HandlerError, bool) {
+	if _, exists := data["idempotencyKey"]; !exists {
+		return "", models.HandlerError{}, true
+	}
+	key, vErr, ok := validate.RequireString(data, "idempotencyKey", false)
+	if !ok {
+		return "", vErr, false
+	}
+	if !idempotencyKeyRe.MatchString(key) {
+		return "", models.HandlerError{
+			Type: "INVALID_TYPE_OR_FORMAT",
+			Code: 5003,
+			Data: map[string]interface{}{
+				"fieldName": "idempotencyKey",
+				"fieldType": "[A-Za-z0-9_:.-]{8,64}",
+			},
+		}, false
+	}
+	return key, models.HandlerError{}, true
+}
+
+// claimIdempotencyKey - Helper
+// holds a user's key on a route until release is called. When the key already has a
+// stored response it is returned as replay and the request must not run again.
+// An empty key claims nothing.
+func claimIdempotencyKey(route string, userID int, key string) (replay *models.HandlerOK, release func()) {
+	if key == "" {
+		return nil, func() {}
+	}
+
+	k := fmt.Sprintf("%d|%s|%s", userID, route, key)
+	inFlightMu.Lock()
+	l, ok := inFlight[k]
+	if !ok {
+		l = &keyLock{}
+		inFlight[k] = l
+	}
+	l.refs++
+	inFlightMu.Unlock()
+
+	l.mu.Lock()
+	release = func() {
+		l.mu.Unlock()
+		inFlightMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(inFlight, k)
+		}
+		inFlightMu.Unlock()
+	}
+
+	if res, found := Store.LoadResult(userID, route, key); found {
+		return res, release
+	}
+	return nil, release
+}
+
+// rememberResult - Helper
+// stores the response of a claimed key; failures are not stored, they moved no money.
+func rememberResult(route string, userID int, key string, res models.HandlerOK) {
+	if key == "" {
+		return
+	}
+	if !Store.SaveResult(userID, route, key, res) {
+		log.Printf("[rememberResult] user %d %s %s not saved", userID, route, key)
+	}
+}
+
+// entryReference - Helper
+// is the UM ledger reference of a user's entry fee for a seat: battle, slot, user and attempt.
+// UM books a reference once, so a debit of the same seat sent twice is taken once. The attempt
+// goes up once an entry is refunded, so a later join of the seat is a new debit instead of
+// matching the refunded one. The idempotency key answers retried requests before they get here.
+func entryReference(b *models.Battle, slotKey string, userID int) string {
+	return fmt.Sprintf("%d-%s-u%d-a%d", b.ID, slotKey, userID, b.EntryTries[entrySeat(slotKey, userID)])
+}
+
+// entrySeat - Helper
+// is the EntryTries key of a user's seat.
+func entrySeat(slotKey string, userID int) string {
+	return fmt.Sprintf("%s-u%d", slotKey, userID)
+}
+
+// nextEntryAttempt - Helper
+// moves a seat's entry reference past an entry that was, or may yet be, refunded.
+func nextEntryAttempt(b *models.Battle, slotKey string, userID int) {
+	if b.EntryTries == nil {
+		b.EntryTries = make(map[string]int)
+	}
+	b.EntryTries[entrySeat(slotKey, userID)]++
+}
+
+// seatEntryReference - Helper
+// is the reference the fee of a seat was taken under; battles saved without EntryRefs used battle ID + slot.
+func seatEntryReference(b *models.Battle, slotKey string) string {
+	if ref, ok := b.EntryRefs[slotKey]; ok {
+		return ref
+	}
+	return fmt.Sprintf("%d-%s", b.ID, slotKey)
+}
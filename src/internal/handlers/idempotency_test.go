@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/standin"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/wallet"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	stored := models.HandlerOK{Type: "join", Data: map[string]interface{}{"emptySlots": float64(0)}}
+	tests := []struct {
+		name     string
+		remember bool // the first request succeeded
+		userID   int
+		route    string
+		key      string
+		replayed bool
+	}{
+		{"stored result replayed", true, 1, "join", "key-00001", true},
+		{"failure not stored", false, 1, "join", "key-00001", false},
+		{"other user", true, 2, "join", "key-00001", false},
+		{"other route", true, 1, "newBattle", "key-00001", false},
+		{"other key", true, 1, "join", "key-00002", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHandlers(t)
+
+			replay, release := claimIdempotencyKey("join", 1, "key-00001")
+			if replay != nil {
+				t.Fatalf("fresh key replayed %v", replay)
+			}
+			if tt.remember {
+				rememberResult("join", 1, "key-00001", stored)
+			}
+			release()
+
+			replay, release = claimIdempotencyKey(tt.route, tt.userID, tt.key)
+			defer release()
+			if (replay != nil) != tt.replayed {
+				t.Fatalf("replay %v, want replayed %v", replay, tt.replayed)
+			}
+			if replay != nil && (replay.Type != stored.Type || fmt.Sprint(replay.Data) != fmt.Sprint(stored.Data)) {
+				t.Errorf("replayed %+v, stored %+v", *replay, stored)
+			}
+		})
+	}
+
+	t.Run("empty key", func(t *testing.T) {
+		setupHandlers(t)
+		rememberResult("join", 1, "", stored)
+		replay, release := claimIdempotencyKey("join", 1, "")
+		defer release()
+		if replay != nil {
+			t.Errorf("empty key replayed %v", replay)
+		}
+	})
+}
+
+func TestClaimIdempotencyKeyConcurrently(t *testing.T) {
+	setupHandlers(t)
+	stored := models.HandlerOK{Type: "join"}
+
+	_, release := claimIdempotencyKey("join", 1, "key-00001")
+
+	// A duplicate waits for the first request, then gets its response
+	got := make(chan *models.HandlerOK)
+	go func() {
+		replay, release := claimIdempotencyKey("join", 1, "key-00001")
+		defer release()
+		got <- replay
+	}()
+	select {
+	case replay := <-got:
+		t.Fatalf("duplicate ran while the first held the key: %v", replay)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rememberResult("join", 1, "key-00001", stored)
+	release()
+	if replay := <-got; replay == nil || replay.Type != stored.Type {
+		t.Errorf("duplicate got %v, want the stored response", replay)
+	}
+
+	inFlightMu.Lock()
+	defer inFlightMu.Unlock()
+	if len(inFlight) != 0 {
+		t.Errorf("%d keys left in flight", len(inFlight))
+	}
+}
+
+func TestJoinIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults int // UM 5xx answers to the first debit, sent again under its reference
+		first  int // slot of a first, failing request with the key; 0 for none
+	}{
+		{name: "duplicates"},
+		{name: "debit 5xx once", faults: 1},
+		{name: "failed request not replayed", first: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			um := setupHandlers(t)
+			um.AddUser("token-creator", 1, "Creator", 100)
+			um.AddUser("token-joiner", 2, "Joiner", 100)
+			created, errR := NewBattle(map[string]interface{}{
+				"token":      "token-creator",
+				"playerType": "2v2",
+				"cases":      []interface{}{map[string]interface{}{"1": float64(1)}},
+			})
+			if errR.Code > 0 {
+				t.Fatalf("newBattle: %s (%d)", errR.Type, errR.Code)
+			}
+			battleID := created.Data.(models.BattleCreated).ID
+			join := func(slotID int) (models.HandlerOK, models.HandlerError) {
+				return Join(map[string]interface{}{
+					"token":          "token-joiner",
+					"battleId":       float64(battleID),
+					"slotId":         float64(slotID),
+					"idempotencyKey": "join-key-1",
+				})
+			}
+
+			if tt.first > 0 {
+				if _, errR := join(tt.first); errR.Code == 0 {
+					t.Fatalf("join of a taken slot went through")
+				}
+			}
+			um.FailNext("xAddTransaction", tt.faults, standin.FaultUnavailable)
+
+			const duplicates = 8
+			var wg sync.WaitGroup
+			responses := make([]string, duplicates)
+			for i := 0; i < duplicates; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					res, errR := join(2)
+					if errR.Code > 0 {
+						responses[i] = fmt.Sprintf("error %s (%d)", errR.Type, errR.Code)
+						return
+					}
+					raw, _ := json.Marshal(res)
+					responses[i] = string(raw)
+				}(i)
+			}
+			wg.Wait()
+
+			for i, res := range responses {
+				if res != responses[0] || res[:5] == "error" {
+					t.Errorf("response %d: %s, first: %s", i, res, responses[0])
+				}
+			}
+			var debits int
+			for _, tx := range um.Transactions() {
+				if tx.UserID == 2 && tx.Type == "game_loss" {
+					debits++
+				}
+			}
+			if debits != 1 || um.Balance(2) != 100-created.Data.(models.BattleCreated).Cost {
+				t.Errorf("%d debits, balance %.2f", debits, um.Balance(2))
+			}
+		})
+	}
+}
+
+func TestEntryReference(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-player", 7, "Player", 100)
+
+	b := &models.Battle{ID: 42, Cost: 10}
+	first := entryReference(b, "s2", 7)
+	if again := entryReference(b, "s2", 7); again != first {
+		t.Fatalf("same seat: %s then %s", first, again)
+	}
+	if other := entryReference(b, "s3", 7); other == first {
+		t.Fatalf("other slot reused %s", first)
+	}
+
+	// A seat debited twice under its reference is taken once
+	for i := 0; i < 2; i++ {
+		if entry, errR := wallet.Reserve(7, b.Cost, first, "Case Battle"); entry == nil {
+			t.Fatalf("reserve %d: %s (%d)", i, errR.Type, errR.Code)
+		}
+	}
+	if um.Balance(7) != 90 {
+		t.Fatalf("balance %.2f after a double debit, want 90", um.Balance(7))
+	}
+
+	// Refunded, the next entry of the seat is a new debit
+	entry, _ := wallet.Reserve(7, b.Cost, first, "Case Battle")
+	refundEntry(b, "s2", entry, "test")
+	second := entryReference(b, "s2", 7)
+	if second == first {
+		t.Fatalf("reference %s reused after a refund", first)
+	}
+	if entry, errR := wallet.Reserve(7, b.Cost, second, "Case Battle"); entry == nil {
+		t.Fatalf("reserve after refund: %s (%d)", errR.Type, errR.Code)
+	}
+	if um.Balance(7) != 90 {
+		t.Errorf("balance %.2f after refund and a new entry, want 90", um.Balance(7))
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// lobbyHistory - deltas kept for resync
+const lobbyHistory = 500
+
+var (
+	lobbyMu     sync.Mutex
+	lobbySeq    uint64
+	lobbyLast   = make(map[int64]models.BattleLobby) // what the lobby was last told
+	lobbyDeltas []models.LobbyDelta                  // the last lobbyHistory deltas, in seq order
+)
+
+// LobbyFeed - Helper
+// diffs the battle index against what the lobby was last told and hands each change to send,
+// in seq order and under the feed lock. The index is read under the lock too, so an older
+// index is never diffed after a newer one and no two callers interleave their deltas.
+func LobbyFeed(send func(models.LobbyDelta)) {
+	lobbyMu.Lock()
+	defer lobbyMu.Unlock()
+
+	current := LobbySummaries(ClientBattleIndex())
+
+	ids := make([]int64, 0, len(current)+len(lobbyLast))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	for id := range lobbyLast {
+		if _, ok := current[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		old, had := lobbyLast[id]
+		cur, has := current[id]
+		var delta models.LobbyDelta
+		switch {
+		case !had:
+			added := cur
+			delta = models.LobbyDelta{Type: "battle.added", BattleID: id, Battle: &added}
+		case !has:
+			delta = models.LobbyDelta{Type: "battle.removed", BattleID: id}
+		default:
+			changes := lobbyChanges(old, cur)
+			if len(changes) == 0 {
+				continue
+			}
+			delta = models.LobbyDelta{Type: "battle.updated", BattleID: id, Changes: changes}
+		}
+
+		lobbySeq++
+		delta.Seq = lobbySeq
+		if has {
+			lobbyLast[id] = cur
+		} else {
+			delete(lobbyLast, id)
+		}
+		lobbyDeltas = append(lobbyDeltas, delta)
+		if len(lobbyDeltas) > lobbyHistory {
+			lobbyDeltas = lobbyDeltas[len(lobbyDeltas)-lobbyHistory:]
+		}
+		send(delta)
+	}
+}
+
+// LobbySnapshot - Helper
+// returns the lobby as of seq; deltas after seq bring it up to date.
+func LobbySnapshot() (uint64, map[int64]models.BattleLobby) {
+	lobbyMu.Lock()
+	defer lobbyMu.Unlock()
+	out := make(map[int64]models.BattleLobby, len(lobbyLast))
+	for id, b := range lobbyLast {
+		out[id] = b
+	}
+	return lobbySeq, out
+}
+
+// lobbyDeltasSince - Helper
+// returns the deltas after seq, or false when some of them are no longer kept.
+func lobbyDeltasSince(seq uint64) ([]models.LobbyDelta, bool) {
+	lobbyMu.Lock()
+	defer lobbyMu.Unlock()
+	if seq > lobbySeq {
+		return nil, false
+	}
+	if seq == lobbySeq {
+		return []models.LobbyDelta{}, true
+	}
+	if len(lobbyDeltas) == 0 || lobbyDeltas[0].Seq > seq+1 {
+		return nil, false
+	}
+	first := int(seq + 1 - lobbyDeltas[0].Seq)
+	return append([]models.LobbyDelta(nil), lobbyDeltas[first:]...), true
+}
+
+// Resync - Handler
+// brings a lobby client back after a gap in seq: the deltas after its seq while they are kept,
+// otherwise a snapshot to start over from.
+func Resync(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Deltas since seq
+	if _, ok := data["seq"]; ok {
+		seq, vErr, ok := validate.RequireInt(data, "seq")
+		if !ok {
+			return resR, vErr
+		}
+		deltas, kept := lobbyDeltasSince(uint64(seq))
+		if seq >= 0 && kept {
+			// Success
+			resR.Type = "resync"
+			resR.Data = map[string]interface{}{
+				"seq":    uint64(seq) + uint64(len(deltas)),
+				"deltas": deltas,
+			}
+			return resR, errR
+		}
+	}
+
+	// Snapshot
+	seq, battles := LobbySnapshot()
+
+	// Success
+	resR.Type = "resync"
+	resR.Data = map[string]interface{}{
+		"seq":     seq,
+		"battles": battles,
+	}
+	return resR, errR
+}
+
+// lobbyChanges - Helper
+// the JSON fields of a lobby summary that differ, by their client names.
+func lobbyChanges(old, cur models.BattleLobby) map[string]interface{} {
+	before, after := lobbyFields(old), lobbyFields(cur)
+	out := make(map[string]interface{})
+	for k, v := range after {
+		if !reflect.DeepEqual(before[k], v) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// lobbyFields - Helper
+func lobbyFields(b models.BattleLobby) map[string]interface{} {
+	var out map[string]interface{}
+	raw, _ := json.Marshal(b)
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/events"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/scheduler"
+	"time"
+)
+
+// Schedule phases, sent in battle.schedule events.
+const (
+	phaseRound   = "round"   // a round is rolled and revealed
+	phaseRolled  = "rolled"  // the last round's reveal is over
+	phaseResolve = "resolve" // the winner is picked
+	phaseRetire  = "retire"  // the archived battle leaves the live index
+)
+
+var (
+	// Pacing - delays between the steps of a battle; main reads it from the env
+	Pacing = scheduler.DefaultConfig()
+
+	// pacer - holds the next step of every battle, one at a time
+	pacer = scheduler.New()
+)
+
+// scheduleRoll - Roll Helper
+// rolls a round once d is over; roundKey == rounds is the step that closes the rolling.
+func scheduleRoll(battleID int64, roundKey, rounds int, d time.Duration) {
+	at := pacer.After(stepKey(battleID), d, func() {
+		Roll(battleID, roundKey)
+	})
+	if roundKey < rounds {
+		emitSchedule(battleID, phaseRound, roundKey+1, at, d)
+	} else {
+		emitSchedule(battleID, phaseRolled, 0, at, d)
+	}
+}
+
+// scheduleResolve - Roll Helper
+func scheduleResolve(battleID int64) {
+	at := pacer.After(stepKey(battleID), Pacing.ResolveDelay, func() {
+		optionActions(battleID)
+	})
+	emitSchedule(battleID, phaseResolve, 0, at, Pacing.ResolveDelay)
+}
+
+// scheduleRetire - Battle Helper
+func scheduleRetire(battleID int) {
+	at := pacer.After(stepKey(int64(battleID)), Pacing.Retention, func() {
+		retire(battleID)
+	})
+	emitSchedule(int64(battleID), phaseRetire, 0, at, Pacing.Retention)
+}
+
+// emitSchedule - Helper
+// tells clients when the next step of a battle happens, so they animate to the server's timing.
+func emitSchedule(battleID int64, phase string, round int, at time.Time, d time.Duration) {
+	data := map[string]interface{}{
+		"phase":   phase,
+		"at":      at.UTC().Format(time.RFC3339Nano),
+		"delayMs": d.Milliseconds(),
+	}
+	if round > 0 {
+		data["round"] = round
+	}
+	events.EmitBattle(battleID, "battle.schedule", data)
+}
+
+// stepKey - Helper
+func stepKey(battleID int64) string {
+	return fmt.Sprintf("battle:%d", battleID)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// payoutBackoff - wait before the first retry of a failed payout, doubled per attempt
+	payoutBackoff = time.Minute
+	// payoutBackoffMax - longest wait between two retries
+	payoutBackoffMax = time.Hour
+	// reconcileBatch - archived battles the reconciler looks at per pass
+	reconcileBatch = 100
+)
+
+// GetPayoutReport - Handler
+// lists battles whose paid game_win total does not match what Summery.Winners owes,
+// with the state of every payout. A battleId reports that battle alone.
+func GetPayoutReport(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Admin Key
+	_, err := utils.ValidateAdminKey(data)
+	if err != nil {
+		errParts := strings.Split(err.Error(), ":")
+		errR.Type = errParts[0]
+		errR.Code, _ = strconv.Atoi(errParts[1])
+		return resR, errR
+	}
+
+	_, single := data["battleId"]
+	report := make([]map[string]interface{}, 0)
+	if single {
+		battleID, vErr, ok := validate.RequireInt(data, "battleId")
+		if !ok {
+			return resR, vErr
+		}
+		battle, errL := loadBattle(battleID)
+		if errL.Code > 0 {
+			return resR, errL
+		}
+		report = append(report, payoutReportEntry(battle))
+	} else {
+		// Rewarded battles still on the index, then the archived ones
+		seen := make(map[int]bool)
+		for _, b := range indexedBattles() {
+			b.MU.Lock()
+			if state := b.CurrentState(); state == models.StateRewarding || state == models.StateArchived {
+				seen[b.ID] = true
+				if mismatched(b) {
+					report = append(report, payoutReportEntry(b))
+				}
+			}
+			b.MU.Unlock()
+		}
+		unpaid, errL := Store.LoadUnpaid(reconcileBatch * 5)
+		if errL.Code > 0 {
+			return resR, errL
+		}
+		for _, b := range unpaid {
+			if !seen[b.ID] && mismatched(b) {
+				report = append(report, payoutReportEntry(b))
+			}
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i]["battleId"].(int) < report[j]["battleId"].(int)
+	})
+
+	// Success
+	resR.Type = "getPayoutReport"
+	resR.Data = map[string]interface{}{
+		"count":   len(report),
+		"battles": report,
+	}
+	return resR, errR
+}
+
+// mismatched - Payout Helper
+func mismatched(b *models.Battle) bool {
+	expected, paid := b.PayoutTotals()
+	return expected != paid || b.Unpaid()
+}
+
+// payoutReportEntry - Payout Helper
+// copies the payouts of a battle for the report; the caller holds b.MU for a live battle.
+func payoutReportEntry(b *models.Battle) map[string]interface{} {
+	expected, paid := b.PayoutTotals()
+	payouts := make(map[string]models.Payout, len(b.Payouts))
+	for key, p := range b.Payouts {
+		payouts[key] = *p
+	}
+	return map[string]interface{}{
+		"battleId": b.ID,
+		"expected": expected,
+		"paid":     paid,
+		"missing":  utils.RoundToTwoDigits(expected - paid),
+		"payouts":  payouts,
+	}
+}
+
+// StartPayoutReconciler - Helper
+// retries failed payouts of archived battles every interval, for the life of the process.
+func StartPayoutReconciler(every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for range ticker.C {
+			ReconcilePayouts()
+		}
+	}()
+}
+
+// ReconcilePayouts - Helper
+// makes one pass over archived battles with unpaid slots and retries those due.
+func ReconcilePayouts() {
+	unpaid, errR := Store.LoadUnpaid(reconcileBatch)
+	if errR.Code > 0 {
+		log.Printf("[ReconcilePayouts] %s (%d)", errR.Type, errR.Code)
+		return
+	}
+	for _, stored := range unpaid {
+		// A battle kept on the index is saved from there, so work on that copy
+		battle, indexed := GetBattle(int64(stored.ID))
+		if !indexed {
+			battle = stored
+		}
+		if indexed {
+			battle.MU.Lock()
+		}
+		if retryPayouts(battle) > 0 {
+			// The store alone: retire may have taken the battle off the index since, it stays off
+			battle.UpdatedAt = time.Now()
+			if errU := Store.Update(battle); errU.Code > 0 {
+				log.Printf("[ReconcilePayouts] battle %d: %s (%d)", battle.ID, errU.Type, errU.Code)
+			}
+		}
+		if indexed {
+			battle.MU.Unlock()
+		}
+	}
+}
+
+// recordPayouts - Payout Helper
+// adds a pending payout for every winning player slot, once per battle.
+func recordPayouts(b *models.Battle) {
+	if b.Payouts != nil {
+		return
+	}
+	b.Payouts = make(map[string]*models.Payout)
+	for _, key := range b.Summery.Winners.Slots {
+		slot := b.Slots[key]
+		if !slot.IsPlayer() {
+			continue
+		}
+		b.Payouts[key] = &models.Payout{
+			UserID:    slot.ID,
+			Amount:    b.Summery.Winners.SlotPrizes,
+			Reference: payoutReference(b.ID, key),
+			State:     models.PayoutPending,
+		}
+	}
+}
+
+// retryPayouts - Payout Helper
+// pays every pending payout and every failed one whose backoff is over; returns how many it tried.
+func retryPayouts(b *models.Battle) int {
+	keys := make([]string, 0, len(b.Payouts))
+	for key := range b.Payouts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tried := 0
+	for _, key := range keys {
+		p := b.Payouts[key]
+		if p.State == models.PayoutPaid || (p.State == models.PayoutFailed && time.Now().Before(p.NextTry)) {
+			continue
+		}
+		payOut(b, key)
+		tried++
+	}
+	return tried
+}
+
+// payOut - Payout Helper
+// sends the game_win of one slot and records the result on its payout and in the battle log.
+func payOut(b *models.Battle, key string) bool {
+	p := b.Payouts[key]
+	p.Attempts++
+
+	// A lost answer (umclient.ErrUncertain) is retried like a failure: same reference, booked once
+	err := sendGameWin(p)
+	if err != nil {
+		p.State = models.PayoutFailed
+		p.LastError = err.Error()
+		p.NextTry = time.Now().Add(payoutRetryAfter(p.Attempts))
+		AddLog(b, "payoutFailed "+key, int64(p.UserID))
+		log.Printf("[payOut] battle %d %s user %d %.2f, try %d: %v", b.ID, key, p.UserID, p.Amount, p.Attempts, err)
+		return false
+	}
+
+	p.State = models.PayoutPaid
+	p.LastError = ""
+	p.NextTry = time.Time{}
+	p.PaidAt = time.Now()
+	AddLog(b, "payout "+key, int64(p.UserID))
+	emitPaid(b, key, p)
+	return true
+}
+
+// sendGameWin - Payout Helper
+func sendGameWin(p *models.Payout) error {
+	err := umclient.AddTransaction(context.Background(), umclient.Transaction{
+		UserID:      p.UserID,
+		Type:        "game_win",
+		ReferenceID: p.Reference,
+		Amount:      p.Amount,
+		Description: "Case Battle",
+	})
+	if err != nil {
+		return err
+	}
+	auth.Forget(int64(p.UserID))
+	return nil
+}
+
+// payoutRetryAfter - Payout Helper
+func payoutRetryAfter(attempts int) time.Duration {
+	wait := payoutBackoff
+	for i := 1; i < attempts && wait < payoutBackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, payoutBackoffMax)
+}
+
+// payoutReference - Payout Helper
+// is the UM ledger reference of a slot's winnings, battle ID + slot; a battle pays each slot once.
+func payoutReference(battleID int, slotKey string) string {
+	return fmt.Sprintf("%d-%s-win", battleID, slotKey)
+}
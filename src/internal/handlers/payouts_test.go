@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/standin"
+	"testing"
+	"time"
+)
+
+const prize = 25.0
+
+// archivedWinner saves an archived battle whose s1 player won prize, with its payout still pending.
+func archivedWinner(t *testing.T, userID int) *models.Battle {
+	t.Helper()
+	b := &models.Battle{
+		PlayerType: "1v1",
+		Cost:       10,
+		State:      models.StateArchived,
+		Slots: map[string]models.Slot{
+			"s1": {ID: userID, DisplayName: "Winner", Type: "Player"},
+			"s2": {ID: 900, DisplayName: "Bot", Type: "Bot"},
+		},
+	}
+	b.Summery.Winners.Slots = []string{"s1"}
+	b.Summery.Winners.SlotPrizes = prize
+	id, errR := Store.Create(b, "seed", "hash")
+	if errR.Code > 0 {
+		t.Fatalf("create: %s (%d)", errR.Type, errR.Code)
+	}
+	b.ID = id
+	recordPayouts(b)
+	if errR := Store.Update(b); errR.Code > 0 {
+		t.Fatalf("update: %s (%d)", errR.Type, errR.Code)
+	}
+	if errR := Store.MarkArchived(id); errR.Code > 0 {
+		t.Fatalf("archive: %s (%d)", errR.Type, errR.Code)
+	}
+	return b
+}
+
+// wins counts the game_win transactions of a user.
+func wins(um *standin.UM, userID int) int {
+	n := 0
+	for _, tx := range um.Transactions() {
+		if tx.UserID == userID && tx.Type == "game_win" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPayoutRetryAfter(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{30, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := payoutRetryAfter(tt.attempts); got != tt.want {
+			t.Errorf("payoutRetryAfter(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestPayOut(t *testing.T) {
+	tests := []struct {
+		name   string
+		faults int
+		fault  standin.Fault
+		paid   bool // by the first try
+		booked bool // UM booked the first try
+	}{
+		{name: "paid", paid: true, booked: true},
+		{name: "5xx", faults: 1, fault: standin.FaultUnavailable},
+		{name: "answer lost", faults: 1, fault: standin.FaultLost, booked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			um := setupHandlers(t)
+			um.AddUser("token-winner", 5, "Winner", 0)
+			b := archivedWinner(t, 5)
+			um.FailNext("xAddTransaction", tt.faults, tt.fault)
+
+			start := time.Now()
+			if paid := payOut(b, "s1"); paid != tt.paid {
+				t.Fatalf("payOut: %v, want %v", paid, tt.paid)
+			}
+			p := b.Payouts["s1"]
+			if n := wins(um, 5); (n == 1) != tt.booked {
+				t.Fatalf("%d game_win booked by the first try", n)
+			}
+			if tt.paid {
+				if p.State != models.PayoutPaid || wins(um, 5) != 1 {
+					t.Fatalf("payout %+v", *p)
+				}
+				return
+			}
+
+			// Failed: backed off, then retried once due
+			if p.State != models.PayoutFailed || p.Attempts != 1 || p.LastError == "" {
+				t.Fatalf("payout %+v", *p)
+			}
+			if wait := p.NextTry.Sub(start); wait < payoutBackoff || wait > payoutBackoff+time.Minute {
+				t.Errorf("next try in %s, want %s", wait, payoutBackoff)
+			}
+			if tried := retryPayouts(b); tried != 0 {
+				t.Errorf("retried %d payouts before their backoff", tried)
+			}
+			p.NextTry = time.Now().Add(-time.Second)
+			if tried := retryPayouts(b); tried != 1 {
+				t.Fatalf("retried %d payouts once due, want 1", tried)
+			}
+			if p.State != models.PayoutPaid || p.Attempts != 2 {
+				t.Errorf("payout after retry %+v", *p)
+			}
+			if tried := retryPayouts(b); tried != 0 {
+				t.Errorf("retried %d paid payouts", tried)
+			}
+			if n := wins(um, 5); n != 1 || um.Balance(5) != prize {
+				t.Errorf("%d game_win, balance %.2f; want 1, %.2f", n, um.Balance(5), prize)
+			}
+		})
+	}
+}
+
+func TestReconcilePayouts(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-winner", 5, "Winner", 0)
+	b := archivedWinner(t, 5)
+	b.Payouts["s1"].State = models.PayoutFailed
+	b.Payouts["s1"].NextTry = time.Now().Add(-time.Second)
+	if errR := Store.Update(b); errR.Code > 0 {
+		t.Fatalf("update: %s (%d)", errR.Type, errR.Code)
+	}
+
+	// A pass pays it, later passes find nothing to pay
+	for pass := 0; pass < 3; pass++ {
+		ReconcilePayouts()
+	}
+	stored, errR := Store.Load(b.ID)
+	if errR.Code > 0 {
+		t.Fatalf("load: %s (%d)", errR.Type, errR.Code)
+	}
+	if p := stored.Payouts["s1"]; p.State != models.PayoutPaid || p.Attempts != 1 {
+		t.Errorf("stored payout %+v", *p)
+	}
+	if n := wins(um, 5); n != 1 || um.Balance(5) != prize {
+		t.Errorf("%d game_win, balance %.2f; want 1, %.2f", n, um.Balance(5), prize)
+	}
+	if _, indexed := GetBattle(int64(b.ID)); indexed {
+		t.Errorf("archived battle %d put on the index", b.ID)
+	}
+}
+
+func TestReconcilePayoutsRetired(t *testing.T) {
+	um := setupHandlers(t)
+	um.AddUser("token-winner", 5, "Winner", 0)
+	b := archivedWinner(t, 5)
+	b.Payouts["s1"].State = models.PayoutFailed
+	b.Payouts["s1"].NextTry = time.Now().Add(-time.Second)
+	if errR := Store.Update(b); errR.Code > 0 {
+		t.Fatalf("update: %s (%d)", errR.Type, errR.Code)
+	}
+	SetBattle(int64(b.ID), b)
+
+	// Retired while the reconciler waits for the battle lock
+	b.MU.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ReconcilePayouts()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	battleIndexMu.Lock()
+	delete(BattleIndex, int64(b.ID))
+	battleIndexMu.Unlock()
+	b.MU.Unlock()
+	<-done
+
+	if _, indexed := GetBattle(int64(b.ID)); indexed {
+		t.Errorf("retired battle %d back on the index", b.ID)
+	}
+	if n := wins(um, 5); n != 1 {
+		t.Errorf("%d game_win, want 1", n)
+	}
+}
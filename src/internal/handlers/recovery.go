@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/wallet"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Recovery stages, from the furthest step a saved battle shows.
+const (
+	stageWaiting = ""        // still taking players, nothing to resume
+	stageRoll    = "roll"    // full, rounds left to roll
+	stageResolve = "resolve" // rolled, no winner yet
+	stageArchive = "archive" // winner picked, payouts and archiving left
+)
+
+var (
+	// recovered - battles already resumed by this process
+	recovered   = make(map[int]bool)
+	recoveredMu sync.Mutex
+
+	// unopened - pending battles FillBattleIndex kept off the index, settled by RecoverBattles
+	unopened   = make(map[int]*models.Battle)
+	unopenedMu sync.Mutex
+)
+
+// RecoverBattles - Helper
+// resumes the live battles whose Roll, optionActions or archive step died with the
+// previous process, cancels the ones that died before they opened and refunds the joins
+// that died before their seat was saved. Run it once after FillBattleIndex; a battle is
+// resumed at most once.
+func RecoverBattles() {
+	unopenedMu.Lock()
+	for id, b := range unopened {
+		delete(unopened, id)
+		go settleUnopened(b)
+	}
+	unopenedMu.Unlock()
+
+	battles := indexedBattles()
+	sort.Slice(battles, func(i, j int) bool { return battles[i].ID < battles[j].ID })
+
+	for _, b := range battles {
+		b.MU.Lock()
+		if len(b.Joining) > 0 {
+			go settleJoins(b)
+		}
+		stage, round := recoveryStage(b)
+		if stage == stageWaiting || !claimRecovery(b.ID) {
+			b.MU.Unlock()
+			continue
+		}
+		log.Printf("[RecoverBattles] battle %d: %s (%s %q, round %d)", b.ID, stage, b.CurrentState(), b.Status, round)
+		AddLog(b, "recover "+stage, 0)
+
+		// Winners picked but the move not saved: they stand, archive pays from resolving
+		if stage == stageArchive && b.CurrentState() == models.StateRolled {
+			if errR := moveBattle(b, models.StateResolving, "Resolving"); errR.Code > 0 {
+				b.MU.Unlock()
+				continue
+			}
+		}
+		UpdateBattle(b)
+		rounds := len(b.Cases)
+		b.MU.Unlock()
+
+		switch stage {
+		case stageRoll:
+			scheduleRoll(int64(b.ID), round, rounds, Pacing.StartDelay)
+		case stageResolve:
+			scheduleResolve(int64(b.ID))
+		case stageArchive:
+			go archive(b.ID)
+		}
+	}
+}
+
+// holdUnopened - Helper
+func holdUnopened(b *models.Battle) {
+	unopenedMu.Lock()
+	defer unopenedMu.Unlock()
+	unopened[b.ID] = b
+}
+
+// settleUnopened - Helper
+// cancels a battle that died pending, once the creator's entry is settled.
+// When UM can not be reached the battle stays pending for the next start.
+func settleUnopened(b *models.Battle) {
+	b.MU.Lock()
+	defer b.MU.Unlock()
+
+	log.Printf("[RecoverBattles] battle %d: settle entry of user %d (%s %q)", b.ID, b.CreatedBy, b.CurrentState(), b.Status)
+	if !settleEntry(b, "s1", seatEntryReference(b, "s1"), b.CreatedBy, "battle never opened") {
+		return
+	}
+	discardBattle(b, "Canceled, never opened")
+}
+
+// settleJoins - Helper
+// frees the slots whose join died between saving them as joining and seating the player,
+// once each entry is settled. Joins UM can not be reached for stay marked for the next start.
+func settleJoins(b *models.Battle) {
+	b.MU.Lock()
+	defer b.MU.Unlock()
+
+	for slotKey, userID := range b.Joining {
+		log.Printf("[RecoverBattles] battle %d: settle join of user %d to %s", b.ID, userID, slotKey)
+		reference := seatEntryReference(b, slotKey)
+		unmarkJoining(b, slotKey)
+		if !settleEntry(b, slotKey, reference, userID, "join never saved") {
+			b.Joining[slotKey] = userID
+			b.EntryRefs[slotKey] = reference
+		}
+	}
+	if errR := Store.Update(b); errR.Code > 0 {
+		log.Printf("[RecoverBattles] battle %d: %s (%d)", b.ID, errR.Type, errR.Code)
+	}
+}
+
+// settleEntry - Helper
+// rolls back an entry fee that died with the previous process. Whether it was taken is not
+// known, so the debit is sent again under its reference, which UM books once, and then
+// refunded. It returns false when UM could not tell and the entry is still unsettled.
+func settleEntry(b *models.Battle, slotKey, reference string, userID int, reason string) bool {
+	entry, errR := wallet.Reserve(userID, b.Cost, reference, "Case Battle")
+	switch {
+	case entry != nil:
+		refundEntry(b, slotKey, entry, reason)
+	case errR.Code == 7001:
+		// Refused for balance: a booked reference would have been answered as done
+		AddLog(b, "entryNotTaken", int64(userID))
+	default:
+		log.Printf("[RecoverBattles] battle %d: entry %s of user %d: %s (%d)", b.ID, reference, userID, errR.Type, errR.Code)
+		return false
+	}
+	return true
+}
+
+// recoveryStage - Helper
+// reads where a saved battle stopped from its state, Summery and logs.
+// For stageRoll it also returns the first round without saved steps.
+func recoveryStage(b *models.Battle) (string, int) {
+	switch b.CurrentState() {
+	case models.StateRolling:
+		round := 0
+		for round < len(b.Cases) && len(b.Summery.Steps[round]) > 0 {
+			round++
+		}
+		return stageRoll, round
+	case models.StateRolled:
+		if len(b.Summery.Winners.Slots) > 0 || hasLog(b, "Handel Options") {
+			return stageArchive, 0
+		}
+		return stageResolve, 0
+	case models.StateResolving, models.StateRewarding:
+		return stageArchive, 0
+	default:
+		return stageWaiting, 0
+	}
+}
+
+// claimRecovery - Helper
+func claimRecovery(battleID int) bool {
+	recoveredMu.Lock()
+	defer recoveredMu.Unlock()
+	if recovered[battleID] {
+		return false
+	}
+	recovered[battleID] = true
+	return true
+}
+
+// hasLog - Helper
+func hasLog(b *models.Battle, action string) bool {
+	for _, l := range b.Logs {
+		if l.Action == action {
+			return true
+		}
+	}
+	return false
+}
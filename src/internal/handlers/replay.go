@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
+	"time"
+)
+
+// replayMaxSpeed - fastest a replay can be played
+const replayMaxSpeed = 10
+
+// replayMaxReveal - a longer gap between rounds is a restart mid-roll, not the pace of the battle
+const replayMaxReveal = time.Minute
+
+// GetBattleReplay - Handler
+// lays out a finished battle round by round, with the slot and team totals and jackpot shares
+// as they stood after each round; the WS replayBattle route streams it at RevealMs.
+func GetBattleReplay(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	battleID, vErr, ok := validate.RequireInt(data, "battleId")
+	if !ok {
+		return resR, vErr
+	}
+
+	speed := 1
+	if _, exists := data["speed"]; exists {
+		v, vErr, ok := validate.RequireInt(data, "speed")
+		if !ok {
+			return resR, vErr
+		}
+		if v < 1 || v > replayMaxSpeed {
+			errR.Type = "INVALID_TYPE_OR_FORMAT"
+			errR.Code = 5003
+			errR.Data = map[string]interface{}{
+				"fieldName": "speed",
+				"fieldType": "int 1-10",
+			}
+			return resR, errR
+		}
+		speed = int(v)
+	}
+
+	battle, errR := loadBattle(battleID)
+	if errR.Code > 0 {
+		return resR, errR
+	}
+
+	// Only battles whose every round and winner are settled
+	if state := battle.CurrentState(); state != models.StateRewarding && state != models.StateArchived {
+		errR.Type = "BATTLE_NOT_FINISHED"
+		errR.Code = 5010
+		return resR, errR
+	}
+
+	// Success
+	resR.Type = "getBattleReplay"
+	resR.Data = battleReplay(battle, speed)
+	return resR, errR
+}
+
+// battleReplay - Battle Helper
+func battleReplay(b *models.Battle, speed int) models.BattleReplay {
+	serverSeedHash, _ := b.PFair["serverSeedHash"].(string)
+	sum := cloneSummery(b.Summery)
+
+	replay := models.BattleReplay{
+		ID:             b.ID,
+		PlayerType:     b.PlayerType,
+		Options:        append([]string(nil), b.Options...),
+		Cases:          append([]int(nil), b.Cases...),
+		CasesUi:        b.CasesUi,
+		Cost:           b.Cost,
+		Slots:          cloneSlots(b.Slots),
+		Teams:          replayTeams(b),
+		ServerSeedHash: serverSeedHash,
+		Speed:          speed,
+		RevealMs:       (originalRoundDelay(b) / time.Duration(speed)).Milliseconds(),
+		Result: &models.ReplayResult{
+			Winners:       sum.Winners,
+			Prizes:        sum.Prizes,
+			Jackpot:       sum.Jackpot,
+			JackpotWinner: sum.JackpotWinner,
+		},
+	}
+
+	teamOf := make(map[string]int)
+	for t, slots := range replay.Teams {
+		for _, slot := range slots {
+			teamOf[slot] = t
+		}
+	}
+	jackpot := utils.InArray(b.Options, "jackpot")
+
+	slotTotals := make(map[string]float64)
+	for roundKey, caseID := range b.Cases {
+		results := sum.Steps[roundKey]
+		for _, step := range results {
+			slotTotals[step.Slot] = utils.RoundToTwoDigits(slotTotals[step.Slot] + step.Price)
+		}
+
+		round := models.ReplayRound{
+			Round:      roundKey + 1,
+			Rounds:     len(b.Cases),
+			CaseID:     caseID,
+			Results:    results,
+			SlotTotals: make(map[string]float64, len(slotTotals)),
+			TeamTotals: make([]float64, len(replay.Teams)),
+		}
+		var pot float64
+		for slot, total := range slotTotals {
+			round.SlotTotals[slot] = total
+			if t, ok := teamOf[slot]; ok {
+				round.TeamTotals[t] = utils.RoundToTwoDigits(round.TeamTotals[t] + total)
+			}
+			pot += total
+		}
+		if jackpot && pot > 0 {
+			round.Jackpot = make(map[string]float64, len(slotTotals))
+			for slot, total := range slotTotals {
+				round.Jackpot[slot] = utils.RoundToTwoDigits((total / pot) * 100)
+			}
+		}
+		replay.Rounds = append(replay.Rounds, round)
+	}
+	return replay
+}
+
+// replayTeams - Battle Helper
+// the slots of each team; battles saved without teams get one team per slot.
+func replayTeams(b *models.Battle) [][]string {
+	var teams [][]string
+	for _, t := range b.Teams {
+		teams = append(teams, append([]string(nil), t.Slots...))
+	}
+	if len(teams) == 0 {
+		for _, key := range slotKeys(b) {
+			teams = append(teams, []string{key})
+		}
+	}
+	return teams
+}
+
+// originalRoundDelay - Battle Helper
+// the time between the reveals of a battle as it was played, read off its rolling stretch in the
+// state log; the configured RoundDelay when the log cannot tell.
+func originalRoundDelay(b *models.Battle) time.Duration {
+	var rolling, rolled time.Time
+	for _, change := range b.StateLog {
+		switch change.To {
+		case models.StateRolling:
+			rolling = change.At
+		case models.StateRolled:
+			rolled = change.At
+		}
+	}
+	rounds := len(b.Cases)
+	if rounds < 2 || rolling.IsZero() || rolled.IsZero() {
+		return Pacing.RoundDelay
+	}
+	d := (rolled.Sub(rolling) - Pacing.StartDelay) / time.Duration(rounds-1)
+	if d <= 0 || d > replayMaxReveal {
+		return Pacing.RoundDelay
+	}
+	return d
+}
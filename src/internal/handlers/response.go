@@ -10,8 +10,23 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"sync"
 )
 
+// wsWriters - one write lock per WS connection, shared with the hub's broadcasts
+var wsWriters sync.Map
+
+// WSWriteLock returns the lock every write to the connection takes.
+func WSWriteLock(conn *websocket.Conn) *sync.Mutex {
+	mu, _ := wsWriters.LoadOrStore(conn, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// ReleaseWSWriteLock forgets the lock of a closed connection.
+func ReleaseWSWriteLock(conn *websocket.Conn) {
+	wsWriters.Delete(conn)
+}
+
 func SendWSResponse(conn *websocket.Conn, reqId int64, resType string, data interface{}) {
 	resp := models.ReqRes{
 		ReqID:  reqId,
@@ -19,7 +34,10 @@ func SendWSResponse(conn *websocket.Conn, reqId int64, resType string, data inte
 		Status: 1,
 		Data:   data,
 	}
+	mu := WSWriteLock(conn)
+	mu.Lock()
 	err := conn.WriteJSON(resp)
+	mu.Unlock()
 	if err != nil {
 		return
 	}
@@ -35,7 +53,10 @@ func SendWSError(conn *websocket.Conn, reqId int64, resType string, eCode int, e
 		Error:  eCode,
 		Data:   eExtra,
 	}
+	mu := WSWriteLock(conn)
+	mu.Lock()
 	err := conn.WriteJSON(resp)
+	mu.Unlock()
 	if err != nil {
 		return
 	}
@@ -80,13 +101,13 @@ func SendWebError(w http.ResponseWriter, resType string, eCode int, eExtra ...an
 
 func EmitServer(resType string) {
 	switch resType {
-	case "test", "getBots", "getCases", "getBattleHistory":
+	case "test", "getBots", "getCases", "getCaseOdds", "getCaseAudit", "getPayoutReport", "getBattleHistory", "getBattleReplay", "verifyBattle", "getSeeds", "rotateSeed", "revokeSession":
 		// no emit
 	default:
 		events.Bus <- events.Event{
-			Target: "all",
+			Target: "index",
 			Type:   "heartbeat",
-			Data:   ClientBattleIndex(BattleIndex),
+			Data:   ClientBattleIndex(),
 		}
 	}
 }
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	userSeeds   = make(map[int]*models.UserSeed)
+	userSeedsMu sync.Mutex
+)
+
+// GetSeeds - Handler
+func GetSeeds(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Token
+	userJWT, vErr, ok := validate.RequireString(data, "token", false)
+	if !ok {
+		return resR, vErr
+	}
+	profile, err := auth.VerifyJWT(userJWT)
+	if err != nil {
+		return resR, umclient.AsHandlerError(err)
+	}
+	userID := int(profile.ID)
+
+	userSeedsMu.Lock()
+	seed := *userSeed(userID)
+	userSeedsMu.Unlock()
+
+	// Success
+	resR.Type = "getSeeds"
+	resR.Data = seed
+	return resR, errR
+}
+
+// RotateSeed - Handler
+// reveals the committed server seed, commits a new one and switches the client seed.
+func RotateSeed(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Token
+	userJWT, vErr, ok := validate.RequireString(data, "token", false)
+	if !ok {
+		return resR, vErr
+	}
+	profile, err := auth.VerifyJWT(userJWT)
+	if err != nil {
+		return resR, umclient.AsHandlerError(err)
+	}
+	userID := int(profile.ID)
+
+	clientSeed, vErr, ok := optionalClientSeed(data)
+	if !ok {
+		return resR, vErr
+	}
+	if clientSeed == "" {
+		clientSeed = provablyfair.GenerateClientSeed()
+	}
+
+	userSeedsMu.Lock()
+	seed := userSeed(userID)
+	seed.PreviousClientSeed = seed.ClientSeed
+	seed.PreviousServerSeed = seed.ServerSeed
+	seed.PreviousServerSeedHash = seed.ServerSeedHash
+	seed.ClientSeed = clientSeed
+	seed.ServerSeed, seed.ServerSeedHash = provablyfair.GenerateServerSeed()
+	seed.RotatedAt = time.Now()
+	saveUserSeed(seed)
+	out := *seed
+	userSeedsMu.Unlock()
+
+	// Success
+	resR.Type = "rotateSeed"
+	resR.Data = out
+	return resR, errR
+}
+
+// optionalClientSeed - Seed Helper
+// reads the optional "clientSeed" field; empty result means the field was not sent.
+func optionalClientSeed(data map[string]interface{}) (string, models.HandlerError, bool) {
+	if _, exists := data["clientSeed"]; !exists {
+		return "", models.HandlerError{}, true
+	}
+	clientSeed, vErr, ok := validate.RequireString(data, "clientSeed", false)
+	if !ok {
+		return "", vErr, false
+	}
+	if !provablyfair.ValidClientSeed(clientSeed) {
+		return "", models.HandlerError{
+			Type: "INVALID_TYPE_OR_FORMAT",
+			Code: 5003,
+			Data: map[string]interface{}{
+				"fieldName": "clientSeed",
+				"fieldType": "[A-Za-z0-9_-]{1,64}",
+			},
+		}, false
+	}
+	return clientSeed, models.HandlerError{}, true
+}
+
+// slotClientSeed - Seed Helper
+// returns the chosen seed, falling back to the user's active client seed.
+func slotClientSeed(chosen string, userID int) string {
+	if chosen != "" {
+		return chosen
+	}
+	userSeedsMu.Lock()
+	defer userSeedsMu.Unlock()
+	return userSeed(userID).ClientSeed
+}
+
+// takeServerSeed - Seed Helper
+// hands the user's committed server seed to a new battle and commits a fresh one in its place.
+// The used seed is revealed with the battle (verifyBattle), not on rotation.
+func takeServerSeed(userID int) (string, string) {
+	userSeedsMu.Lock()
+	defer userSeedsMu.Unlock()
+	seed := userSeed(userID)
+	serverSeed, serverSeedHash := seed.ServerSeed, seed.ServerSeedHash
+	seed.ServerSeed, seed.ServerSeedHash = provablyfair.GenerateServerSeed()
+	saveUserSeed(seed)
+	return serverSeed, serverSeedHash
+}
+
+// userSeed - Seed Helper
+// returns the cached seed pair of a user, loading or creating it; userSeedsMu must be held.
+func userSeed(userID int) *models.UserSeed {
+	if seed, ok := userSeeds[userID]; ok {
+		return seed
+	}
+	seed, ok := loadUserSeed(userID)
+	if !ok {
+		seed = &models.UserSeed{
+			UserID:     userID,
+			ClientSeed: provablyfair.GenerateClientSeed(),
+			RotatedAt:  time.Now(),
+		}
+		seed.ServerSeed, seed.ServerSeedHash = provablyfair.GenerateServerSeed()
+		saveUserSeed(seed)
+	}
+	userSeeds[userID] = seed
+	return seed
+}
+
+// loadUserSeed - Seed Helper
+func loadUserSeed(userID int) (*models.UserSeed, bool) {
+	return Store.LoadUserSeed(userID)
+}
+
+// saveUserSeed - Seed Helper
+func saveUserSeed(seed *models.UserSeed) {
+	if !Store.SaveUserSeed(seed) {
+		log.Println("failed to save user seed:", seed.UserID)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
+	"strconv"
+	"strings"
+)
+
+// Bind - Handler
+// verifies the token a WS connection binds to; the hub attaches the connection to Data["userId"].
+func Bind(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Token
+	userJWT, vErr, ok := validate.RequireString(data, "token", false)
+	if !ok {
+		return resR, vErr
+	}
+	profile, err := auth.VerifyJWT(userJWT)
+	if err != nil {
+		return resR, umclient.AsHandlerError(err)
+	}
+
+	// Success
+	resR.Type = "bind.ok"
+	resR.Data = map[string]interface{}{
+		"userId":      profile.ID,
+		"displayName": profile.DisplayName,
+	}
+	return resR, errR
+}
+
+// RevokeSession - Handler
+// drops a revoked session from the auth cache, by its token or for every token of a user.
+func RevokeSession(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Check Admin Key
+	_, err := utils.ValidateAdminKey(data)
+	if err != nil {
+		errParts := strings.Split(err.Error(), ":")
+		errR.Type = errParts[0]
+		errR.Code, _ = strconv.Atoi(errParts[1])
+		return resR, errR
+	}
+
+	if _, ok := data["sessionToken"]; ok {
+		sessionToken, vErr, ok := validate.RequireString(data, "sessionToken", false)
+		if !ok {
+			return resR, vErr
+		}
+		auth.Revoke(sessionToken)
+	} else {
+		userID, vErr, ok := validate.RequireInt(data, "userId")
+		if !ok {
+			return resR, vErr
+		}
+		auth.RevokeUser(userID)
+	}
+
+	// Success
+	resR.Type = "revokeSession"
+	resR.Data = map[string]interface{}{
+		"revoked": true,
+	}
+	return resR, errR
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/events"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+)
+
+// Battle events, sent as each step happens so spectators don't have to diff the heartbeat index.
+// Every one is called under the battle lock and sends copies, the hub encodes them later.
+
+// emitRoundStarted - Roll Helper
+func emitRoundStarted(b *models.Battle, roundKey int) {
+	events.EmitBattle(int64(b.ID), "battle.roundStarted", map[string]interface{}{
+		"round":  roundKey + 1,
+		"rounds": len(b.Cases),
+		"caseId": b.Cases[roundKey],
+	})
+}
+
+// emitRoundResult - Roll Helper
+// sends the StepResults of a rolled round and how long clients have to reveal them.
+func emitRoundResult(b *models.Battle, roundKey int) {
+	events.EmitBattle(int64(b.ID), "battle.roundResult", map[string]interface{}{
+		"round":    roundKey + 1,
+		"rounds":   len(b.Cases),
+		"results":  append([]models.StepResult(nil), b.Summery.Steps[roundKey]...),
+		"revealMs": Pacing.RoundDelay.Milliseconds(),
+	})
+}
+
+// emitResolved - Battle Helper
+func emitResolved(b *models.Battle) {
+	sum := cloneSummery(b.Summery)
+	events.EmitBattle(int64(b.ID), "battle.resolved", map[string]interface{}{
+		"winners":       sum.Winners,
+		"prizes":        sum.Prizes,
+		"jackpotWinner": sum.JackpotWinner,
+	})
+}
+
+// emitPaid - Payout Helper
+func emitPaid(b *models.Battle, key string, p *models.Payout) {
+	events.EmitBattle(int64(b.ID), "battle.paid", map[string]interface{}{
+		"slot":        key,
+		"userId":      p.UserID,
+		"displayName": b.Slots[key].DisplayName,
+		"amount":      p.Amount,
+		"paidAt":      p.PaidAt,
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+)
+
+// LobbyRoom - the room of connections watching the battle list
+const LobbyRoom = "lobby"
+
+// BattleRoom - the room of connections watching one battle
+func BattleRoom(battleID int64) string {
+	return fmt.Sprintf("battle:%d", battleID)
+}
+
+// Subscribe - Handler
+// validates a room and returns its first snapshot; the WS hub adds the connection to Data["room"].
+func Subscribe(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	// Battle Room
+	if _, ok := data["battleId"]; ok {
+		battleId, vErr, ok := validate.RequireInt(data, "battleId")
+		if !ok {
+			return resR, vErr
+		}
+		battle, ok := GetBattle(battleId)
+		if !ok {
+			errR.Type = "NOT_FOUND"
+			errR.Code = 5003
+			return resR, errR
+		}
+		battle.MU.Lock()
+		snapshot := ClientBattle(battle)
+		snapshot.CreatedBy = battle.CreatedBy
+		battle.MU.Unlock()
+
+		// Success
+		resR.Type = "subscribe"
+		resR.Data = map[string]interface{}{
+			"room":   BattleRoom(battleId),
+			"battle": snapshot,
+		}
+		return resR, errR
+	}
+
+	// Lobby
+	if _, vErr, ok := validate.RequireStringIn(data, "room", []string{LobbyRoom}); !ok {
+		return resR, vErr
+	}
+
+	// Success - the lobby as of seq, the room's deltas carry on from there
+	seq, battles := LobbySnapshot()
+	resR.Type = "subscribe"
+	resR.Data = map[string]interface{}{
+		"room":    LobbyRoom,
+		"seq":     seq,
+		"battles": battles,
+	}
+	return resR, errR
+}
+
+// Unsubscribe - Handler
+// names the room to leave; a battle may already be off the index.
+func Unsubscribe(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	room := LobbyRoom
+	if _, ok := data["battleId"]; ok {
+		battleId, vErr, ok := validate.RequireInt(data, "battleId")
+		if !ok {
+			return resR, vErr
+		}
+		room = BattleRoom(battleId)
+	} else if _, vErr, ok := validate.RequireStringIn(data, "room", []string{LobbyRoom}); !ok {
+		return resR, vErr
+	}
+
+	// Success
+	resR.Type = "unsubscribe"
+	resR.Data = map[string]interface{}{
+		"room": room,
+	}
+	return resR, errR
+}
+
+// LobbySummaries - Helper
+// shrinks a client index to what the lobby lists.
+func LobbySummaries(index map[int64]models.BattleClient) map[int64]models.BattleLobby {
+	out := make(map[int64]models.BattleLobby, len(index))
+	for id, b := range index {
+		taken := 0
+		for _, s := range b.Slots {
+			if s.Type != "Empty" {
+				taken++
+			}
+		}
+		out[id] = models.BattleLobby{
+			ID:         b.ID,
+			PlayerType: b.PlayerType,
+			Options:    b.Options,
+			CaseCounts: b.CaseCounts,
+			Cost:       b.Cost,
+			Seats:      len(b.Slots),
+			Taken:      taken,
+			Status:     b.Status,
+			StatusCode: b.StatusCode,
+			CreatedAt:  b.CreatedAt,
+		}
+	}
+	return out
+}
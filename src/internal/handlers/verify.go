@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/provablyfair"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
+)
+
+// maxReplaySearch bounds the forward nonce search used to reproduce re-rolls.
+const maxReplaySearch = 1000
+
+// VerifyBattle - Handler
+// reveals the server seed of a finished battle and replays every step.
+func VerifyBattle(data map[string]interface{}) (models.HandlerOK, models.HandlerError) {
+	var (
+		errR models.HandlerError
+		resR models.HandlerOK
+	)
+
+	if len(CasesImpacted) == 0 {
+		FillCaseImpact()
+	}
+
+	battleID, vErr, ok := validate.RequireInt(data, "battleId")
+	if !ok {
+		return resR, vErr
+	}
+
+	battle, errR := loadBattle(battleID)
+	if errR.Code > 0 {
+		return resR, errR
+	}
+
+	// Seeds are revealed only once the rolls can no longer change
+	if state := battle.CurrentState(); state != models.StateRewarding && state != models.StateArchived {
+		errR.Type = "BATTLE_NOT_FINISHED"
+		errR.Code = 5010
+		return resR, errR
+	}
+
+	serverSeed, _ := battle.PFair["serverSeed"].(string)
+	serverSeedHash, _ := battle.PFair["serverSeedHash"].(string)
+	clientSeeds, _ := battle.PFair["clientSeed"].(map[string]interface{})
+
+	result := models.VerifyResult{
+		BattleID:       battle.ID,
+		ServerSeed:     serverSeed,
+		ServerSeedHash: serverSeedHash,
+		HashValid:      provablyfair.VerifyServerSeed(serverSeed, serverSeedHash),
+		Steps:          []models.VerifyStep{},
+	}
+	result.Verified = result.HashValid
+
+	scheme := provablyfair.NonceSchemes[provablyfair.NonceSchemeV1]
+	if v, ok := pFairInt(battle.PFair, "nonceScheme"); ok {
+		if s, found := provablyfair.NonceSchemes[v]; found {
+			scheme = s
+			result.NonceScheme = s.Version
+		}
+	}
+	rng := battleRNG(battle)
+	picker := battlePicker(battle)
+	result.RNG = rng
+	result.PickModel = picker.Model
+	result.PayoutCurve = picker.Curve
+	_, result.CasesPinned = pinnedCases(battle)
+
+	for roundKey, caseID := range battle.Cases {
+		caseData := battleCase(battle, caseID)
+		nonce := scheme.Round(roundKey)
+		for _, step := range battle.Summery.Steps[roundKey] {
+			clientSeed, _ := clientSeeds[step.Slot].(string)
+			nonce = scheme.Slot(nonce)
+
+			var vStep models.VerifyStep
+			if result.NonceScheme > 0 {
+				vStep = verifyStep(scheme, picker, caseData, clientSeed, &nonce, step)
+			} else {
+				vStep = replayStep(rng, caseData, serverSeed, clientSeed, &nonce, step)
+			}
+			vStep.Round = roundKey + 1
+			vStep.CaseID = caseID
+			if !vStep.Match {
+				result.Verified = false
+			}
+			result.Steps = append(result.Steps, vStep)
+		}
+	}
+
+	// Success
+	resR.Type = "verifyBattle"
+	resR.Data = result
+	return resR, errR
+}
+
+// verifyStep - Verify Helper
+// checks the recorded draws of a step against the nonce scheme and regenerates each of them.
+func verifyStep(scheme provablyfair.NonceScheme, picker provablyfair.Picker, c models.Case, clientSeed string, nonce *int, step models.StepResult) models.VerifyStep {
+	draws := rejectDraws(step, "")
+	next, chainOK := scheme.CheckDraws(*nonce, draws)
+	*nonce = next
+
+	vStep := models.VerifyStep{
+		Slot:           step.Slot,
+		ClientSeed:     clientSeed,
+		Nonce:          step.Nonce,
+		Roll:           step.Roll,
+		RecordedItemID: step.ItemID,
+		Attempts:       len(step.Attempts),
+		Match:          chainOK,
+	}
+	for _, d := range draws {
+		roll, item := picker.Replay(c, clientSeed, d.Nonce)
+		if roll != d.Roll || itemID(item) != d.ItemID {
+			vStep.Match = false
+		}
+		// Only battles rolled before payout curves have HE re-draws
+		if d.Reason == provablyfair.ReasonHE {
+			vStep.Adjusted = true
+		}
+		vStep.ItemID = itemID(item)
+		vStep.Price = itemPrice(item)
+	}
+	if vStep.ItemID != step.ItemID {
+		vStep.Match = false
+	}
+	return vStep
+}
+
+// replayStep - Verify Helper
+// regenerates one step of a battle rolled before nonces were recorded, deriving them the way Roll did.
+func replayStep(rng int, c models.Case, serverSeed, clientSeed string, nonce *int, step models.StepResult) models.VerifyStep {
+	roll, item := provablyfair.ReplayItem(rng, c, serverSeed, clientSeed, *nonce)
+
+	// No range matched: Roll kept stepping the nonce by 7
+	for i := 0; item == nil && i < maxReplaySearch; i++ {
+		*nonce += 7
+		roll, item = provablyfair.ReplayItem(rng, c, serverSeed, clientSeed, *nonce)
+	}
+
+	vStep := models.VerifyStep{
+		Slot:           step.Slot,
+		ClientSeed:     clientSeed,
+		Nonce:          *nonce,
+		Roll:           roll,
+		RecordedItemID: step.ItemID,
+	}
+	if item == nil {
+		return vStep
+	}
+	vStep.ItemID = itemID(item)
+	vStep.Price = itemPrice(item)
+	vStep.Match = vStep.ItemID == step.ItemID
+	if vStep.Match {
+		return vStep
+	}
+
+	// HE adjustment, gone since payout curves: the re-roll model walked the nonce up until the item fit the case price
+	casePrice := c.Price.Float()
+	if vStep.Price <= casePrice {
+		return vStep
+	}
+	for n := *nonce + 1; n <= *nonce+maxReplaySearch; n++ {
+		r, adjusted := provablyfair.ReplayItem(rng, c, serverSeed, clientSeed, n)
+		if adjusted == nil || itemPrice(adjusted) > casePrice {
+			continue
+		}
+		if itemID(adjusted) == step.ItemID {
+			vStep.Nonce = n
+			vStep.Roll = r
+			vStep.ItemID = step.ItemID
+			vStep.Price = itemPrice(adjusted)
+			vStep.Adjusted = true
+			vStep.Match = true
+		}
+		break
+	}
+	return vStep
+}
+
+// itemID - Verify Helper
+func itemID(item *models.CaseItem) int {
+	if item == nil {
+		return 0
+	}
+	return item.ID
+}
+
+// itemPrice - Verify Helper
+func itemPrice(item *models.CaseItem) float64 {
+	if item == nil {
+		return 0
+	}
+	return item.Price.Float()
+}
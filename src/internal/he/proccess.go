@@ -1,11 +1,5 @@
 package he
 
-import (
-	"fmt"
-	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
-	"log"
-)
-
 // Tracker keeps track of financial stats for a single game (income, expense, ROI, HE).
 type Tracker struct {
 	Income  float64
@@ -54,33 +48,8 @@ func (t *Tracker) CalHouseEdge() {
 	t.HE = (t.Income - t.Expense) / t.Income * 100
 }
 
-// Save computes ROI and HE, then persists all values to the database.
-func (t *Tracker) Save(gameTable string, gameID int) {
+// Finalize computes ROI and HE from the tracked income and expense.
+func (t *Tracker) Finalize() {
 	t.calRatio()
 	t.CalHouseEdge()
-
-	query := fmt.Sprintf(
-		`UPDATE %s SET income=%.2f, expense=%.2f, roi=%.2f, he=%.2f WHERE id=%d`,
-		gameTable,
-		t.Income,
-		t.Expense,
-		t.ROI,
-		t.HE,
-		gameID,
-	)
-	log.Println(query)
-
-	grpcclient.SendQuery(query)
-
-}
-
-func _Example() {
-
-	tracker := NewTracker()
-
-	tracker.AddIncome(1000)
-	tracker.AddExpense(930)
-
-	tracker.Save("g1_games", 123)
-
 }
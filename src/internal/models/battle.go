@@ -14,11 +14,27 @@ type Slot struct {
 	Team        int    `json:"team"`
 }
 
+// IsPlayer reports whether a user sits in the slot; joined seats were once saved as "Players".
+func (s Slot) IsPlayer() bool {
+	return s.Type == "Player" || s.Type == "Players"
+}
+
+// Draw is a single RNG call made while picking an item.
+type Draw struct {
+	Nonce  int    `json:"nonce"`
+	Roll   int    `json:"roll"`
+	ItemID int    `json:"itemId"`
+	Reason string `json:"reason,omitempty"` // why the draw was rejected
+}
+
 type StepResult struct {
 	Slot       string  `json:"slot"`   // s1, s2, ...
 	ItemID     int     `json:"itemId"` // ID
 	Price      float64 `json:"price"`
 	Percentage float64 `json:"percentage"`
+	Nonce      int     `json:"nonce"`              // nonce of the winning draw
+	Roll       int     `json:"roll"`               // raw FairRand value of the winning draw
+	Attempts   []Draw  `json:"attempts,omitempty"` // rejected draws, in order
 }
 
 type Summery struct {
@@ -49,6 +65,8 @@ type Battle struct {
 	Bots       []int                  `json:"bots"`
 	Status     string                 `json:"status"`
 	StatusCode int                    `json:"statusCode"`
+	State      BattleState            `json:"state"`
+	StateLog   []StateChange          `json:"stateLog,omitempty"`
 	Summery    Summery                `json:"summery"`
 	CreatedAt  time.Time              `json:"createdAt"`
 	UpdatedAt  time.Time              `json:"updatedAt"`
@@ -58,6 +76,10 @@ type Battle struct {
 	Logs       []BattleLog            `json:"logs"`
 	PrivateKey string                 `json:"privateKey"`
 	Teams      []Team                 `json:"teams"`
+	Payouts    map[string]*Payout     `json:"payouts,omitempty"`    // winning player slot → game_win
+	EntryRefs  map[string]string      `json:"entryRefs,omitempty"`  // player slot → UM reference of its entry fee
+	Joining    map[string]int         `json:"joining,omitempty"`    // slot → user whose entry fee is being taken for it
+	EntryTries map[string]int         `json:"entryTries,omitempty"` // slot-u<user> → entry fees of that seat refunded so far
 	MU         sync.Mutex             `json:"-"`
 	Tracker    *he.Tracker            `json:"-"`
 }
@@ -112,9 +134,114 @@ type BattleClient struct {
 	ServerSeedHash string           `json:"serverSeedHash"`
 }
 
+// BattleLobby is the compact summary of a battle the lobby room gets.
+type BattleLobby struct {
+	ID         int       `json:"id"`
+	PlayerType string    `json:"playerType"`
+	Options    []string  `json:"options"`
+	CaseCounts int       `json:"caseCounts"`
+	Cost       float64   `json:"cost"`
+	Seats      int       `json:"seats"`
+	Taken      int       `json:"taken"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"statusCode"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// LobbyDelta is one change of the lobby feed; clients apply them in Seq order.
+type LobbyDelta struct {
+	Seq      uint64                 `json:"seq"`
+	Type     string                 `json:"type"` // battle.added / battle.updated / battle.removed
+	BattleID int64                  `json:"battleId"`
+	Battle   *BattleLobby           `json:"battle,omitempty"`  // added
+	Changes  map[string]interface{} `json:"changes,omitempty"` // updated, changed fields only
+}
+
 type Team struct {
 	Slots       []string
 	SlotPrizes  float64
 	TotalPrizes float64
 	RolWin      int64
 }
+
+type VerifyStep struct {
+	Round          int     `json:"round"`
+	Slot           string  `json:"slot"`
+	CaseID         int     `json:"caseId"`
+	ClientSeed     string  `json:"clientSeed"`
+	Nonce          int     `json:"nonce"`
+	Roll           int     `json:"roll"`
+	ItemID         int     `json:"itemId"`
+	RecordedItemID int     `json:"recordedItemId"`
+	Price          float64 `json:"price"`
+	Attempts       int     `json:"attempts"` // rejected draws replayed
+	Adjusted       bool    `json:"adjusted"` // HE re-roll reproduced
+	Match          bool    `json:"match"`
+}
+
+// PayoutCurve scales, in permille, the weight of items worth more than their case.
+type PayoutCurve struct {
+	Name   string `json:"name"`
+	Factor int    `json:"factor"`
+}
+
+type VerifyResult struct {
+	BattleID       int          `json:"battleId"`
+	ServerSeed     string       `json:"serverSeed"`
+	ServerSeedHash string       `json:"serverSeedHash"`
+	HashValid      bool         `json:"hashValid"`
+	NonceScheme    int          `json:"nonceScheme"` // 0 = not recorded, nonces derived
+	RNG            int          `json:"rng"`
+	PickModel      int          `json:"pickModel"`
+	PayoutCurve    PayoutCurve  `json:"payoutCurve"`
+	CasesPinned    bool         `json:"casesPinned"` // replayed against the case tables of the battle, not the current ones
+	Steps          []VerifyStep `json:"steps"`
+	Verified       bool         `json:"verified"`
+}
+
+// BattleReplay is a finished battle laid out round by round, for rewatching it.
+type BattleReplay struct {
+	ID             int              `json:"id"`
+	PlayerType     string           `json:"playerType"`
+	Options        []string         `json:"options"`
+	Cases          []int            `json:"cases"`
+	CasesUi        []map[string]int `json:"casesUi"`
+	Cost           float64          `json:"cost"`
+	Slots          map[string]Slot  `json:"slots"`
+	Teams          [][]string       `json:"teams"` // slots of each team, TeamTotals are in this order
+	ServerSeedHash string           `json:"serverSeedHash"`
+	Speed          int              `json:"speed"`
+	RevealMs       int64            `json:"revealMs"` // between rounds, the original pace over Speed
+	Rounds         []ReplayRound    `json:"rounds,omitempty"`
+	Result         *ReplayResult    `json:"result,omitempty"`
+}
+
+// ReplayRound is one round of a replay and the standings once it was revealed.
+type ReplayRound struct {
+	Round      int                `json:"round"`
+	Rounds     int                `json:"rounds"`
+	CaseID     int                `json:"caseId"`
+	Results    []StepResult       `json:"results"`
+	SlotTotals map[string]float64 `json:"slotTotals"`        // s1 → won so far
+	TeamTotals []float64          `json:"teamTotals"`        // won so far, by team
+	Jackpot    map[string]float64 `json:"jackpot,omitempty"` // s1 → % of the pot so far, jackpot battles only
+}
+
+// ReplayResult is how a replayed battle ended.
+type ReplayResult struct {
+	Winners       Team               `json:"winners"`
+	Prizes        map[string]float64 `json:"prizes"`
+	Jackpot       map[string]float64 `json:"jackpot,omitempty"`
+	JackpotWinner string             `json:"jackpotWinner"`
+}
+
+type UserSeed struct {
+	UserID                 int       `json:"userId"`
+	ClientSeed             string    `json:"clientSeed"`
+	ServerSeed             string    `json:"-"` // committed, revealed on rotation
+	ServerSeedHash         string    `json:"serverSeedHash"`
+	PreviousClientSeed     string    `json:"previousClientSeed,omitempty"`
+	PreviousServerSeed     string    `json:"previousServerSeed,omitempty"`
+	PreviousServerSeedHash string    `json:"previousServerSeedHash,omitempty"`
+	RotatedAt              time.Time `json:"rotatedAt"`
+}
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in cents. Prices arrive as DECIMAL strings and are kept exact.
+type Money int64
+
+// ParseMoney reads a decimal amount such as "12.5" or "-0.07"; more than two decimals are rounded.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+	neg := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimLeft(s, "+-"), ".")
+	if whole == "" {
+		whole = "0"
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	cents := w * 100
+	if frac != "" {
+		for _, c := range frac {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("invalid amount %q", s)
+			}
+		}
+		digits := (frac + "00")[:2]
+		f, _ := strconv.ParseInt(digits, 10, 64)
+		cents += f
+		if len(frac) > 2 && frac[2] >= '5' {
+			cents++
+		}
+	}
+	if neg {
+		cents = -cents
+	}
+	return Money(cents), nil
+}
+
+// MoneyFromFloat converts a float amount, rounding to the nearest cent.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Float returns the amount in currency units, for balances and totals kept as float64.
+func (m Money) Float() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals, like the DB does.
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// MarshalJSON keeps prices as decimal strings on the wire.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a number.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		*m = v
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("invalid amount %s", b)
+	}
+	*m = MoneyFromFloat(f)
+	return nil
+}
+
+// CaseItem is one row of case_items joined with its g1_items skin.
+type CaseItem struct {
+	ID             int    `json:"id"` // case_items.id, recorded as StepResult.ItemID
+	CaseID         int    `json:"case_id"`
+	ItemID         int    `json:"item_id"` // g1_items.item_orginal_id
+	MinRand        int    `json:"min_rand"`
+	MaxRand        int    `json:"max_rand"`
+	Price          Money  `json:"price"`
+	Rarity         string `json:"rarity"`
+	Color          string `json:"color"`
+	MarketHashName string `json:"market_hash_name"`
+	Category       string `json:"category"`
+	Wear           string `json:"wear"`
+}
+
+// Case is a published case with its items sorted by roll range.
+type Case struct {
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Color        string     `json:"color"`
+	Price        Money      `json:"price"`
+	Distribution string     `json:"distribution"`
+	Rarity       string     `json:"rarity"`
+	Weight       float64    `json:"weight"`
+	Items        []CaseItem `json:"items"`
+}
+
+// SortItems orders the items by MinRand, then ID.
+func (c *Case) SortItems() {
+	sort.Slice(c.Items, func(i, j int) bool {
+		if c.Items[i].MinRand != c.Items[j].MinRand {
+			return c.Items[i].MinRand < c.Items[j].MinRand
+		}
+		return c.Items[i].ID < c.Items[j].ID
+	})
+}
+
+// MarshalJSON keeps the getCases wire shape: items is an object keyed by case_items id.
+func (c Case) MarshalJSON() ([]byte, error) {
+	type plain Case
+	items := make(map[int]CaseItem, len(c.Items))
+	for _, item := range c.Items {
+		items[item.ID] = item
+	}
+	return json.Marshal(struct {
+		plain
+		Items map[int]CaseItem `json:"items"`
+	}{plain(c), items})
+}
+
+// UnmarshalJSON reads items keyed by id, as written by MarshalJSON, or as a list, and sorts them.
+func (c *Case) UnmarshalJSON(b []byte) error {
+	type plain Case
+	var raw struct {
+		plain
+		Items json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*c = Case(raw.plain)
+	c.Items = nil
+
+	items := strings.TrimSpace(string(raw.Items))
+	switch {
+	case strings.HasPrefix(items, "{"):
+		var byID map[int]CaseItem
+		if err := json.Unmarshal(raw.Items, &byID); err != nil {
+			return err
+		}
+		for _, item := range byID {
+			c.Items = append(c.Items, item)
+		}
+	case strings.HasPrefix(items, "["):
+		if err := json.Unmarshal(raw.Items, &c.Items); err != nil {
+			return err
+		}
+	}
+	c.SortItems()
+	return nil
+}
+
+// ItemForRoll returns the first item, in range order, whose MinRand..MaxRand contains the roll.
+func (c Case) ItemForRoll(roll int) (*CaseItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].MinRand > roll {
+			break
+		}
+		if roll <= c.Items[i].MaxRand {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// Item returns an item of the case by its case_items id.
+func (c Case) Item(id int) (*CaseItem, bool) {
+	for i := range c.Items {
+		if c.Items[i].ID == id {
+			return &c.Items[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "12.50", want: 1250},
+		{in: "12.5", want: 1250},
+		{in: "12", want: 1200},
+		{in: " 7.07 ", want: 707},
+		{in: ".5", want: 50},
+		{in: "+3.10", want: 310},
+		{in: "0.994", want: 99},
+		{in: "0.995", want: 100},
+		{in: "1.005", want: 101},
+		{in: "19.999", want: 2000},
+		{in: "-0.07", want: -7},
+		{in: "-0.075", want: -8}, // away from zero
+		{in: "-12.344", want: -1234},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1.x", wantErr: true},
+		{in: "1e3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1250, "12.50"},
+		{-7, "-0.07"},
+		{-1234, "-12.34"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCaseJSON(t *testing.T) {
+	c := Case{
+		ID:    1,
+		Name:  "Starter",
+		Price: 1000,
+		Items: []CaseItem{
+			{ID: 101, CaseID: 1, MinRand: 0, MaxRand: 599_999, Price: 200},
+			{ID: 102, CaseID: 1, MinRand: 600_000, MaxRand: 1_000_000, Price: 1550},
+		},
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// getCases has always sent items keyed by case_items id
+	var wire struct {
+		Items map[string]map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &wire); err != nil {
+		t.Fatalf("items are not an object keyed by id: %v\n%s", err, b)
+	}
+	if len(wire.Items) != 2 || wire.Items["102"]["price"] != "15.50" || wire.Items["101"]["id"] != float64(101) {
+		t.Errorf("items %v", wire.Items)
+	}
+
+	var back Case
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, c) {
+		t.Errorf("round trip %+v, want %+v", back, c)
+	}
+
+	// Lists, as in the fixtures and battles pinned before, are read in range order
+	list := `{"id":1,"name":"Starter","price":"10.00","items":[
+		{"id":102,"case_id":1,"min_rand":600000,"max_rand":1000000,"price":"15.50"},
+		{"id":101,"case_id":1,"min_rand":0,"max_rand":599999,"price":"2.00"}]}`
+	var fromList Case
+	if err := json.Unmarshal([]byte(list), &fromList); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromList, c) {
+		t.Errorf("from list %+v, want %+v", fromList, c)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Payout states of a winning player slot.
+const (
+	PayoutPending = "pending" // recorded, game_win not confirmed yet
+	PayoutPaid    = "paid"
+	PayoutFailed  = "failed" // game_win refused or unreachable, retried by the reconciler
+)
+
+// Payout is the game_win transaction owed to one winning player slot.
+type Payout struct {
+	UserID    int       `json:"userId"`
+	Amount    float64   `json:"amount"`
+	Reference string    `json:"reference"`
+	State     string    `json:"state"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError,omitempty"`
+	NextTry   time.Time `json:"nextTry,omitempty"`
+	PaidAt    time.Time `json:"paidAt,omitempty"`
+}
+
+// Unpaid reports whether any payout of the battle is not paid yet.
+func (b *Battle) Unpaid() bool {
+	for _, p := range b.Payouts {
+		if p.State != PayoutPaid {
+			return true
+		}
+	}
+	return false
+}
+
+// PayoutTotals returns what the winners are owed by Summery.Winners and what the payouts have paid.
+func (b *Battle) PayoutTotals() (expected, paid float64) {
+	for _, key := range b.Summery.Winners.Slots {
+		if b.Slots[key].IsPlayer() {
+			expected += b.Summery.Winners.SlotPrizes
+		}
+	}
+	for _, p := range b.Payouts {
+		if p.State == PayoutPaid {
+			paid += p.Amount
+		}
+	}
+	return math.Round(expected*100) / 100, math.Round(paid*100) / 100
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BattleState is where a battle is in its life. StatusCode is kept next to it for clients.
+type BattleState string
+
+const (
+	StatePending   BattleState = "pending"   // saved, creator's entry not taken yet; not listed
+	StateWaiting   BattleState = "waiting"   // taking players
+	StateRolling   BattleState = "rolling"   // seats full, rounds being rolled
+	StateRolled    BattleState = "rolled"    // every round rolled
+	StateResolving BattleState = "resolving" // winner picked
+	StateRewarding BattleState = "rewarding" // paying winners
+	StateArchived  BattleState = "archived"
+	StateCanceled  BattleState = "canceled"
+)
+
+// stateCodes - the StatusCode clients know for each state
+var stateCodes = map[BattleState]int{
+	StatePending:   0,
+	StateWaiting:   0,
+	StateRolling:   0,
+	StateRolled:    1,
+	StateResolving: 2,
+	StateRewarding: 3,
+	StateArchived:  -1,
+	StateCanceled:  -2,
+}
+
+// transitions - the states a state may move to; moving to itself only changes Status
+var transitions = map[BattleState][]BattleState{
+	"":             {StatePending, StateWaiting},
+	StatePending:   {StateWaiting, StateCanceled},
+	StateWaiting:   {StateWaiting, StateRolling, StateCanceled},
+	StateRolling:   {StateRolling, StateRolled},
+	StateRolled:    {StateResolving},
+	StateResolving: {StateRewarding},
+	StateRewarding: {StateRewarding, StateArchived},
+	StateArchived:  {},
+	StateCanceled:  {},
+}
+
+// StateChange records one move between two states.
+type StateChange struct {
+	From BattleState `json:"from"`
+	To   BattleState `json:"to"`
+	At   time.Time   `json:"at"`
+}
+
+// TransitionError is a move the state machine refuses.
+type TransitionError struct {
+	From BattleState
+	To   BattleState
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("battle can not move from %q to %q", e.From, e.To)
+}
+
+// CurrentState returns the battle state, reading it from StatusCode for battles saved before State existed.
+func (b *Battle) CurrentState() BattleState {
+	if b.State != "" {
+		return b.State
+	}
+	switch b.StatusCode {
+	case 0:
+		if b.Status == "" {
+			return "" // new, not moved yet
+		}
+		if strings.HasPrefix(b.Status, "Waiting") {
+			return StateWaiting
+		}
+		return StateRolling
+	case 1:
+		return StateRolled
+	case 2:
+		return StateResolving
+	case 3:
+		return StateRewarding
+	case -1:
+		return StateArchived
+	case -2:
+		return StateCanceled
+	}
+	return ""
+}
+
+// CanMove reports whether the battle may move to a state.
+func (b *Battle) CanMove(to BattleState) bool {
+	from := b.CurrentState()
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Move takes the battle to a state with a Status text for clients, or refuses with a *TransitionError.
+// Every state change is kept in StateLog; a move to the same state only updates Status.
+func (b *Battle) Move(to BattleState, status string) error {
+	from := b.CurrentState()
+	if !b.CanMove(to) {
+		return &TransitionError{From: from, To: to}
+	}
+	if from != to {
+		b.StateLog = append(b.StateLog, StateChange{From: from, To: to, At: time.Now().UTC()})
+	}
+	b.State = to
+	b.StatusCode = stateCodes[to]
+	b.Status = status
+	return nil
+}
@@ -0,0 +1,102 @@
+package provablyfair
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+)
+
+// NonceSchemeV1 is the nonce schedule Roll has always used, now pinned so stored battles can be replayed.
+//
+// For a round r (0-based) the shared round nonce n walks as follows:
+//
+//	round base : n = (r+7)*2 + r
+//	slot draw  : n += 97 before each slot, in Summery.Steps order
+//	miss       : n += 7 while no item range contains the roll
+//	re-roll    : n += 297 for a last-round tie break
+//	HE adjust  : d = d+1, d+2, ...; n does not move
+//
+// HE adjusts are only found in battles rolled with PickModelReRoll, before payout curves;
+// Roll no longer makes them and they are kept so those battles still verify.
+const NonceSchemeV1 = 1
+
+// CurrentNonceScheme is recorded in Battle.PFair["nonceScheme"] when rolling starts.
+const CurrentNonceScheme = NonceSchemeV1
+
+// Draw reasons, recorded on the rejected attempts of a step.
+// ReasonHE is only read back when verifying battles rolled before payout curves.
+const (
+	ReasonMiss   = "miss"
+	ReasonHE     = "he"
+	ReasonReRoll = "reroll"
+)
+
+// NonceScheme describes how nonces advance within a round.
+type NonceScheme struct {
+	Version     int `json:"version"`
+	RoundOffset int `json:"roundOffset"`
+	SlotStep    int `json:"slotStep"`
+	MissStep    int `json:"missStep"`
+	ReRollStep  int `json:"reRollStep"`
+	AdjustStep  int `json:"adjustStep"`
+}
+
+// NonceSchemes - all published schemes by version
+var NonceSchemes = map[int]NonceScheme{
+	NonceSchemeV1: {
+		Version:     NonceSchemeV1,
+		RoundOffset: 7,
+		SlotStep:    97,
+		MissStep:    7,
+		ReRollStep:  297,
+		AdjustStep:  1,
+	},
+}
+
+// Draw is a single FairRand call made while picking an item.
+type Draw = models.Draw
+
+// Round returns the base nonce of a round.
+func (s NonceScheme) Round(round int) int {
+	return ((round + s.RoundOffset) * 2) + round
+}
+
+// Slot returns the nonce of the next slot draw.
+func (s NonceScheme) Slot(n int) int {
+	return n + s.SlotStep
+}
+
+// Miss returns the nonce after a draw that matched no item.
+func (s NonceScheme) Miss(n int) int {
+	return n + s.MissStep
+}
+
+// ReRoll returns the nonce after a last-round tie break.
+func (s NonceScheme) ReRoll(n int) int {
+	return n + s.ReRollStep
+}
+
+// Adjust returns the next nonce tried by a house edge adjustment of a pre-curve battle.
+func (s NonceScheme) Adjust(n int) int {
+	return n + s.AdjustStep
+}
+
+// CheckDraws verifies that draws follow the scheme starting from the slot nonce n.
+// It returns the round nonce after the step and whether the chain is intact.
+func (s NonceScheme) CheckDraws(n int, draws []Draw) (int, bool) {
+	expected := n
+	for _, d := range draws {
+		if d.Nonce != expected {
+			return n, false
+		}
+		switch d.Reason {
+		case ReasonHE:
+			expected = s.Adjust(d.Nonce)
+		case ReasonMiss:
+			n = s.Miss(n)
+			expected = n
+		case ReasonReRoll:
+			n = s.ReRoll(n)
+			expected = n
+		}
+	}
+	return n, true
+}
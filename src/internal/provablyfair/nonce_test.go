@@ -0,0 +1,72 @@
+package provablyfair
+
+import (
+	"testing"
+)
+
+func TestNonceSchemeV1(t *testing.T) {
+	s := NonceSchemes[NonceSchemeV1]
+	// Published values: changing any of them breaks the verification of stored battles
+	if got := s.Round(0); got != 14 {
+		t.Errorf("Round(0) = %d, want 14", got)
+	}
+	if got := s.Round(3); got != 23 {
+		t.Errorf("Round(3) = %d, want 23", got)
+	}
+	if s.Slot(14) != 111 || s.Miss(111) != 118 || s.ReRoll(111) != 408 || s.Adjust(111) != 112 {
+		t.Errorf("steps %+v", s)
+	}
+}
+
+func TestCheckDraws(t *testing.T) {
+	s := NonceSchemes[NonceSchemeV1]
+	const n = 111
+	tests := []struct {
+		name  string
+		draws []Draw
+		next  int
+		ok    bool
+	}{
+		{name: "no draws", next: n, ok: true},
+		{name: "first draw kept", draws: []Draw{{Nonce: n}}, next: n, ok: true},
+		{
+			name:  "misses move the round nonce",
+			draws: []Draw{{Nonce: n, Reason: ReasonMiss}, {Nonce: n + 7, Reason: ReasonMiss}, {Nonce: n + 14}},
+			next:  n + 14,
+			ok:    true,
+		},
+		{
+			name:  "re-roll moves the round nonce",
+			draws: []Draw{{Nonce: n, Reason: ReasonReRoll}, {Nonce: n + 297}},
+			next:  n + 297,
+			ok:    true,
+		},
+		{
+			name:  "pre-curve HE re-draws leave the round nonce",
+			draws: []Draw{{Nonce: n, Reason: ReasonHE}, {Nonce: n + 1, Reason: ReasonHE}, {Nonce: n + 2}},
+			next:  n,
+			ok:    true,
+		},
+		{
+			name:  "miss after HE re-draws",
+			draws: []Draw{{Nonce: n, Reason: ReasonHE}, {Nonce: n + 1, Reason: ReasonMiss}, {Nonce: n + 7}},
+			next:  n + 7,
+			ok:    true,
+		},
+		{name: "wrong first nonce", draws: []Draw{{Nonce: n + 1}}},
+		{name: "skipped miss", draws: []Draw{{Nonce: n, Reason: ReasonMiss}, {Nonce: n + 8}}},
+		{name: "HE step from the round nonce", draws: []Draw{{Nonce: n, Reason: ReasonHE}, {Nonce: n + 2}}},
+		{name: "re-roll taken as a miss", draws: []Draw{{Nonce: n, Reason: ReasonReRoll}, {Nonce: n + 7}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := s.CheckDraws(n, tt.draws)
+			if ok != tt.ok {
+				t.Fatalf("CheckDraws ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && next != tt.next {
+				t.Errorf("CheckDraws next = %d, want %d", next, tt.next)
+			}
+		})
+	}
+}
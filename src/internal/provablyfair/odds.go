@@ -0,0 +1,177 @@
+package provablyfair
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"math"
+	"sort"
+)
+
+// Pick models, recorded in Battle.PFair["pickModel"].
+const (
+	// PickModelReRoll - legacy, verify only: battles rolled before payout curves drew over
+	// min_rand/max_rand and re-drew expensive items under low HE. Roll always pins PickModelCurve.
+	PickModelReRoll = 1
+	// PickModelCurve - roll over a published odds table reshaped by a payout curve.
+	PickModelCurve = 2
+)
+
+// RollSpace is the range min_rand/max_rand are configured on: 0..1,000,000.
+const RollSpace = 1_000_001
+
+// MinCurveFactor bounds every curve: an item never drops below 25% of its configured weight.
+const MinCurveFactor = 250
+
+// PayoutCurve scales, in permille, the weight of items worth more than their case.
+type PayoutCurve = models.PayoutCurve
+
+// PayoutBand selects a curve while the recent house edge is at or above MinHE.
+type PayoutBand struct {
+	MinHE float64     `json:"minHE"`
+	Curve PayoutCurve `json:"curve"`
+}
+
+// Curves - all published curves
+var (
+	CurveNeutral = PayoutCurve{Name: "neutral", Factor: 1000}
+	CurveTight   = PayoutCurve{Name: "tight", Factor: 500}
+	CurveStrict  = PayoutCurve{Name: "strict", Factor: MinCurveFactor}
+)
+
+// PayoutPolicy is checked top-down; a zero HE means no history yet and stays neutral.
+var PayoutPolicy = []PayoutBand{
+	{MinHE: 8, Curve: CurveNeutral},
+	{MinHE: 0, Curve: CurveTight},
+	{MinHE: -math.MaxFloat64, Curve: CurveStrict},
+}
+
+// Curves returns every curve of the policy by name.
+func Curves() map[string]PayoutCurve {
+	out := map[string]PayoutCurve{CurveNeutral.Name: CurveNeutral}
+	for _, band := range PayoutPolicy {
+		out[band.Curve.Name] = band.Curve
+	}
+	return out
+}
+
+// CurveForHE returns the curve the policy applies at a house edge.
+func CurveForHE(HE float64) PayoutCurve {
+	if HE == 0 {
+		return CurveNeutral
+	}
+	for _, band := range PayoutPolicy {
+		if HE >= band.MinHE {
+			return band.Curve
+		}
+	}
+	return CurveNeutral
+}
+
+// OddsEntry is one item of a probability table; the roll wins it when Min <= roll <= Max.
+type OddsEntry struct {
+	ItemID      int          `json:"itemId"`
+	Price       models.Money `json:"price"`
+	Weight      int          `json:"weight"`
+	Min         int          `json:"min"`
+	Max         int          `json:"max"`
+	Probability float64      `json:"probability"`
+}
+
+// OddsTable is the published distribution of a case under one curve.
+// Rolls are drawn in [0, Total).
+type OddsTable struct {
+	CaseID    int          `json:"caseId"`
+	CasePrice models.Money `json:"casePrice"`
+	Curve     PayoutCurve  `json:"curve"`
+	Total     int          `json:"total"`
+	RTP       float64      `json:"rtp"` // expected item value / case price, in percent
+	Entries   []OddsEntry  `json:"entries"`
+}
+
+// BuildOddsTable derives a case table from min_rand/max_rand widths, in the case's range order.
+// Items priced above the case get weight*Factor/1000, never below MinCurveFactor.
+func BuildOddsTable(c models.Case, curve PayoutCurve) OddsTable {
+	factor := curve.Factor
+	if factor < MinCurveFactor {
+		factor = MinCurveFactor
+	}
+
+	table := OddsTable{
+		CaseID:    c.ID,
+		CasePrice: c.Price,
+		Curve:     PayoutCurve{Name: curve.Name, Factor: factor},
+	}
+
+	var expected float64
+	for _, item := range c.Items {
+		minR := max(item.MinRand, 0)
+		maxR := min(item.MaxRand, RollSpace-1)
+		if maxR < minR {
+			continue
+		}
+		entry := OddsEntry{
+			ItemID: item.ID,
+			Price:  item.Price,
+			Weight: maxR - minR + 1,
+		}
+		if entry.Price > table.CasePrice {
+			entry.Weight = entry.Weight * factor / 1000
+		}
+		if entry.Weight == 0 {
+			continue
+		}
+		entry.Min = table.Total
+		entry.Max = table.Total + entry.Weight - 1
+		table.Total += entry.Weight
+		table.Entries = append(table.Entries, entry)
+	}
+	for i := range table.Entries {
+		p := float64(table.Entries[i].Weight) / float64(table.Total)
+		table.Entries[i].Probability = p
+		expected += p * table.Entries[i].Price.Float()
+	}
+	if table.CasePrice > 0 {
+		table.RTP = math.Round(expected/table.CasePrice.Float()*10000) / 100
+	}
+	return table
+}
+
+// Lookup returns the entry a roll falls into.
+func (t OddsTable) Lookup(roll int) (OddsEntry, bool) {
+	i := sort.Search(len(t.Entries), func(i int) bool { return t.Entries[i].Max >= roll })
+	if i < len(t.Entries) && t.Entries[i].Min <= roll {
+		return t.Entries[i], true
+	}
+	return OddsEntry{}, false
+}
+
+// Picker draws items for one battle with everything recorded in its PFair.
+type Picker struct {
+	Model      int
+	RNG        int
+	Curve      PayoutCurve
+	ServerSeed string
+}
+
+// Pick draws the item of one slot; nil means the case has nothing to draw.
+func (p Picker) Pick(c models.Case, clientSeed string, nonce int) (*models.CaseItem, []Draw) {
+	roll, item := p.Replay(c, clientSeed, nonce)
+	return item, []Draw{{Nonce: nonce, Roll: roll, ItemID: drawItemID(item)}}
+}
+
+// Replay regenerates a single draw of this battle.
+func (p Picker) Replay(c models.Case, clientSeed string, nonce int) (int, *models.CaseItem) {
+	if p.Model != PickModelCurve {
+		return ReplayItem(p.RNG, c, p.ServerSeed, clientSeed, nonce)
+	}
+	table := BuildOddsTable(c, p.Curve)
+	if table.Total == 0 {
+		return 0, nil
+	}
+	roll := RNG(p.RNG)(p.ServerSeed, clientSeed, nonce, table.Total)
+	entry, ok := table.Lookup(roll)
+	if !ok {
+		return roll, nil
+	}
+	item, _ := c.Item(entry.ItemID)
+	return roll, item
+}
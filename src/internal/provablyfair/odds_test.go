@@ -0,0 +1,139 @@
+package provablyfair
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"math"
+	"testing"
+)
+
+// oddsCase costs 10.00: a 5.00 item on 60% of the rolls, a 20.00 item on 30% and a 50.00 item on 10%.
+func oddsCase() models.Case {
+	return models.Case{
+		ID:    3,
+		Price: 1000,
+		Items: []models.CaseItem{
+			{ID: 31, MinRand: 0, MaxRand: 599_999, Price: 500},
+			{ID: 32, MinRand: 600_000, MaxRand: 899_999, Price: 2000},
+			{ID: 33, MinRand: 900_000, MaxRand: 1_000_000, Price: 5000},
+		},
+	}
+}
+
+func TestCurveForHE(t *testing.T) {
+	tests := []struct {
+		he   float64
+		want string
+	}{
+		{0, "neutral"}, // no history yet
+		{25, "neutral"},
+		{8, "neutral"},
+		{7.99, "tight"},
+		{0.01, "tight"},
+		{-0.01, "strict"},
+		{-40, "strict"},
+	}
+	for _, tt := range tests {
+		if got := CurveForHE(tt.he); got.Name != tt.want {
+			t.Errorf("CurveForHE(%v) = %s, want %s", tt.he, got.Name, tt.want)
+		}
+	}
+}
+
+func TestBuildOddsTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		curve   PayoutCurve
+		factor  int
+		weights []int
+		rtp     float64
+	}{
+		{name: "neutral", curve: CurveNeutral, factor: 1000, weights: []int{600_000, 300_000, 100_001}, rtp: 140},
+		{name: "tight", curve: CurveTight, factor: 500, weights: []int{600_000, 150_000, 50_000}, rtp: 106.25},
+		{name: "strict", curve: CurveStrict, factor: 250, weights: []int{600_000, 75_000, 25_000}, rtp: 82.14},
+		{name: "clamped", curve: PayoutCurve{Name: "zero", Factor: 0}, factor: MinCurveFactor, weights: []int{600_000, 75_000, 25_000}, rtp: 82.14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := BuildOddsTable(oddsCase(), tt.curve)
+			if table.Curve.Factor != tt.factor {
+				t.Errorf("factor %d, want %d", table.Curve.Factor, tt.factor)
+			}
+			if len(table.Entries) != len(tt.weights) {
+				t.Fatalf("%d entries, want %d", len(table.Entries), len(tt.weights))
+			}
+
+			total, next := 0, 0
+			var probability float64
+			for i, e := range table.Entries {
+				if e.Weight != tt.weights[i] {
+					t.Errorf("item %d weight %d, want %d", e.ItemID, e.Weight, tt.weights[i])
+				}
+				if e.Min != next || e.Max != next+e.Weight-1 {
+					t.Errorf("item %d covers %d..%d, want %d..%d", e.ItemID, e.Min, e.Max, next, next+e.Weight-1)
+				}
+				next = e.Max + 1
+				total += e.Weight
+				probability += e.Probability
+			}
+			if table.Total != total {
+				t.Errorf("total %d, want %d", table.Total, total)
+			}
+			if math.Abs(probability-1) > 1e-9 {
+				t.Errorf("probabilities sum to %v", probability)
+			}
+			if math.Abs(table.RTP-tt.rtp) > 0.01 {
+				t.Errorf("RTP %.2f, want %.2f", table.RTP, tt.rtp)
+			}
+		})
+	}
+}
+
+func TestBuildOddsTableSkips(t *testing.T) {
+	c := models.Case{
+		ID:    4,
+		Price: 1000,
+		Items: []models.CaseItem{
+			{ID: 41, MinRand: -10, MaxRand: 9, Price: 100},              // clipped to 0..9
+			{ID: 42, MinRand: 20, MaxRand: 10, Price: 100},              // inverted
+			{ID: 43, MinRand: 10, MaxRand: 10, Price: 5000},             // 1*250/1000 rounds to nothing
+			{ID: 44, MinRand: 999_990, MaxRand: 1_000_500, Price: 1000}, // clipped to the roll space
+		},
+	}
+	table := BuildOddsTable(c, CurveStrict)
+	want := []struct{ id, weight int }{{41, 10}, {44, 11}}
+	if len(table.Entries) != len(want) {
+		t.Fatalf("entries %+v", table.Entries)
+	}
+	for i, w := range want {
+		if e := table.Entries[i]; e.ItemID != w.id || e.Weight != w.weight {
+			t.Errorf("entry %d is item %d weight %d, want item %d weight %d", i, e.ItemID, e.Weight, w.id, w.weight)
+		}
+	}
+	if table.Total != 21 {
+		t.Errorf("total %d, want 21", table.Total)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	table := BuildOddsTable(oddsCase(), CurveTight)
+	tests := []struct {
+		roll int
+		item int
+		ok   bool
+	}{
+		{0, 31, true},
+		{599_999, 31, true},
+		{600_000, 32, true},
+		{749_999, 32, true},
+		{750_000, 33, true},
+		{table.Total - 1, 33, true},
+		{table.Total, 0, false},
+		{-1, 0, false},
+	}
+	for _, tt := range tests {
+		e, ok := table.Lookup(tt.roll)
+		if ok != tt.ok || e.ItemID != tt.item {
+			t.Errorf("Lookup(%d) = item %d, %v; want item %d, %v", tt.roll, e.ItemID, ok, tt.item, tt.ok)
+		}
+	}
+}
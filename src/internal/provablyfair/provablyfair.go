@@ -6,12 +6,12 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
-	"log"
-	"strconv"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
 )
 
 // FairRand generates a deterministic "random" number based on server seed, client seed and nonce.
 // max: the upper limit of the random number (exclusive)
+// This is RNG v1 (RNGSha256V1); it carries a small modulo bias, new battles use FairRandV2.
 func FairRand(serverSeed, clientSeed string, nonce, max int) int {
 	input := fmt.Sprintf("%s:%s:%d", serverSeed, clientSeed, nonce)
 	hash := sha256.Sum256([]byte(input))
@@ -19,81 +19,63 @@ func FairRand(serverSeed, clientSeed string, nonce, max int) int {
 	return int(num) % max
 }
 
-func PickItem(HE float64, caseData map[string]interface{}, serverSeed, clientSeed string, nonce int) map[string]interface{} {
-
-	selectedItem := selectItem(caseData, serverSeed, clientSeed, nonce)
-	priceStr, _ := selectedItem["price"].(string)
-	price, _ := strconv.ParseFloat(priceStr, 64)
-
-	casePricestr, _ := caseData["price"].(string)
-	casePrice, _ := strconv.ParseFloat(casePricestr, 64)
-
-	if HE > 8 || HE == 0 {
-		return selectedItem
+func GenerateServerSeed() (string, string) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
 	}
+	seed := hex.EncodeToString(bytes)
+	return seed, HashServerSeed(seed) // (serverSeed, serverSeedHash)
+}
 
-	if HE > 0 && HE < 8 {
-		if price > casePrice {
-			for {
-				selectedItem = selectItem(caseData, serverSeed, clientSeed, nonce)
-				priceStr, _ = selectedItem["price"].(string)
-				price, _ = strconv.ParseFloat(priceStr, 64)
-				if price <= casePrice {
-					break
-				}
-				nonce++
-			}
-			return selectedItem
-		}
-		return selectedItem
+// GenerateClientSeed returns a random default client seed for players who have not chosen one.
+func GenerateClientSeed() string {
+	bytes := make([]byte, 16)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		panic(err)
 	}
+	return hex.EncodeToString(bytes)
+}
 
-	if HE < 0 {
-		if price > casePrice {
-			for {
-				selectedItem = selectItem(caseData, serverSeed, clientSeed, nonce)
-				priceStr, _ = selectedItem["price"].(string)
-				price, _ = strconv.ParseFloat(priceStr, 64)
-				if price <= casePrice {
-					break
-				}
-				nonce++
-			}
-			return selectedItem
+// ValidClientSeed accepts 1..64 chars of [A-Za-z0-9_-]; ':' is reserved as the FairRand separator.
+func ValidClientSeed(clientSeed string) bool {
+	if len(clientSeed) == 0 || len(clientSeed) > 64 {
+		return false
+	}
+	for _, c := range clientSeed {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+		default:
+			return false
 		}
-		return selectedItem
 	}
+	return true
+}
 
-	return nil
+// HashServerSeed returns the public commitment (hex SHA-256) of a server seed.
+func HashServerSeed(serverSeed string) string {
+	hash := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(hash[:])
 }
 
-func GenerateServerSeed() (string, string) {
-	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes)
-	if err != nil {
-		panic(err)
-	}
-	seed := hex.EncodeToString(bytes)
-	hash := sha256.Sum256([]byte(seed))
-	return seed, hex.EncodeToString(hash[:]) // (serverSeed, serverSeedHash)
+// VerifyServerSeed checks a revealed server seed against its published hash.
+func VerifyServerSeed(serverSeed, serverSeedHash string) bool {
+	return serverSeed != "" && HashServerSeed(serverSeed) == serverSeedHash
 }
 
-func selectItem(caseData map[string]interface{}, serverSeed, clientSeed string, nonce int) map[string]interface{} {
+// ReplayItem regenerates a single draw, returning the raw roll and the matching item (nil if no range matches).
+func ReplayItem(rng int, c models.Case, serverSeed, clientSeed string, nonce int) (int, *models.CaseItem) {
 	// Generate provably fair random number 0..1,000,000
-	r := FairRand(serverSeed, clientSeed, nonce, 1_000_001)
-	itemsRaw, ok := caseData["items"].(map[int]map[string]interface{})
-	if !ok {
-		log.Println("PickItem > no items")
-		return nil
-	}
-	// Select matching item
-	for _, item := range itemsRaw {
-		minR, _ := item["min_rand"].(float64)
-		maxR, _ := item["max_rand"].(float64)
+	r := RNG(rng)(serverSeed, clientSeed, nonce, RollSpace)
+	item, _ := c.ItemForRoll(r)
+	return r, item
+}
 
-		if float64(r) >= minR && float64(r) <= maxR {
-			return item
-		}
+func drawItemID(item *models.CaseItem) int {
+	if item == nil {
+		return 0
 	}
-	return nil
+	return item.ID
 }
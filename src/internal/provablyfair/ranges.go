@@ -0,0 +1,87 @@
+package provablyfair
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"sort"
+)
+
+// Range issue kinds, reported by CheckRanges.
+const (
+	IssueNoItems    = "no_items"
+	IssueOutOfRange = "out_of_range"
+	IssueInverted   = "inverted"
+	IssueOverlap    = "overlap"
+	IssueGap        = "gap"
+)
+
+// RangeIssue is one problem of a case's min_rand/max_rand configuration.
+// For overlaps Min..Max is the shared part; for gaps it is the uncovered part.
+type RangeIssue struct {
+	Kind        string `json:"kind"`
+	ItemID      int    `json:"itemId,omitempty"`
+	OtherItemID int    `json:"otherItemId,omitempty"`
+	Min         int    `json:"min"`
+	Max         int    `json:"max"`
+}
+
+// CheckRanges reports every roll in 0..RollSpace-1 that no item or more than one item covers,
+// plus bounds that are inverted or outside the roll space. No issues means every
+// roll maps to exactly one item.
+func CheckRanges(c models.Case) []RangeIssue {
+	var issues []RangeIssue
+
+	if len(c.Items) == 0 {
+		return []RangeIssue{{Kind: IssueNoItems, Max: RollSpace - 1}}
+	}
+
+	type bounds struct {
+		id, min, max int
+	}
+	var ranges []bounds
+	for _, item := range c.Items {
+		b := bounds{id: item.ID, min: item.MinRand, max: item.MaxRand}
+		if b.max < b.min {
+			issues = append(issues, RangeIssue{Kind: IssueInverted, ItemID: b.id, Min: b.min, Max: b.max})
+			continue
+		}
+		if b.min < 0 || b.max > RollSpace-1 {
+			issues = append(issues, RangeIssue{Kind: IssueOutOfRange, ItemID: b.id, Min: b.min, Max: b.max})
+			b.min = max(b.min, 0)
+			b.max = min(b.max, RollSpace-1)
+			if b.max < b.min {
+				continue
+			}
+		}
+		ranges = append(ranges, b)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].min != ranges[j].min {
+			return ranges[i].min < ranges[j].min
+		}
+		return ranges[i].id < ranges[j].id
+	})
+
+	// Walk the sorted ranges keeping the end of the covered part and the item reaching it
+	covered, coveredBy := -1, 0
+	for _, b := range ranges {
+		if b.min > covered+1 {
+			issues = append(issues, RangeIssue{Kind: IssueGap, Min: covered + 1, Max: b.min - 1})
+		}
+		if b.min <= covered {
+			issues = append(issues, RangeIssue{
+				Kind:        IssueOverlap,
+				ItemID:      b.id,
+				OtherItemID: coveredBy,
+				Min:         b.min,
+				Max:         min(b.max, covered),
+			})
+		}
+		if b.max > covered {
+			covered, coveredBy = b.max, b.id
+		}
+	}
+	if covered < RollSpace-1 {
+		issues = append(issues, RangeIssue{Kind: IssueGap, Min: covered + 1, Max: RollSpace - 1})
+	}
+	return issues
+}
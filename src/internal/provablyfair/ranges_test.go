@@ -0,0 +1,90 @@
+package provablyfair
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestCheckRanges(t *testing.T) {
+	const top = RollSpace - 1
+	item := func(id, min, max int) models.CaseItem {
+		return models.CaseItem{ID: id, MinRand: min, MaxRand: max}
+	}
+	tests := []struct {
+		name  string
+		items []models.CaseItem
+		want  []RangeIssue
+	}{
+		{
+			name:  "covered once",
+			items: []models.CaseItem{item(1, 0, 499_999), item(2, 500_000, top)},
+		},
+		{
+			name:  "covered once, out of order",
+			items: []models.CaseItem{item(2, 500_000, top), item(1, 0, 499_999)},
+		},
+		{
+			name: "no items",
+			want: []RangeIssue{{Kind: IssueNoItems, Max: top}},
+		},
+		{
+			name:  "gap between items",
+			items: []models.CaseItem{item(1, 0, 99), item(2, 200, top)},
+			want:  []RangeIssue{{Kind: IssueGap, Min: 100, Max: 199}},
+		},
+		{
+			name:  "gaps at both ends",
+			items: []models.CaseItem{item(1, 10, 999_990)},
+			want:  []RangeIssue{{Kind: IssueGap, Min: 0, Max: 9}, {Kind: IssueGap, Min: 999_991, Max: top}},
+		},
+		{
+			name:  "overlap",
+			items: []models.CaseItem{item(1, 0, 600), item(2, 500, top)},
+			want:  []RangeIssue{{Kind: IssueOverlap, ItemID: 2, OtherItemID: 1, Min: 500, Max: 600}},
+		},
+		{
+			name:  "item inside another",
+			items: []models.CaseItem{item(1, 0, top), item(2, 10, 20)},
+			want:  []RangeIssue{{Kind: IssueOverlap, ItemID: 2, OtherItemID: 1, Min: 10, Max: 20}},
+		},
+		{
+			name:  "shared bound",
+			items: []models.CaseItem{item(1, 0, 500), item(2, 500, top)},
+			want:  []RangeIssue{{Kind: IssueOverlap, ItemID: 2, OtherItemID: 1, Min: 500, Max: 500}},
+		},
+		{
+			name:  "past the roll space",
+			items: []models.CaseItem{item(1, 0, 499_999), item(2, 500_000, 1_000_100)},
+			want:  []RangeIssue{{Kind: IssueOutOfRange, ItemID: 2, Min: 500_000, Max: 1_000_100}},
+		},
+		{
+			name:  "below zero",
+			items: []models.CaseItem{item(1, -5, 499_999), item(2, 500_000, top)},
+			want:  []RangeIssue{{Kind: IssueOutOfRange, ItemID: 1, Min: -5, Max: 499_999}},
+		},
+		{
+			name:  "wholly past the roll space",
+			items: []models.CaseItem{item(1, 0, top), item(2, RollSpace, RollSpace+10)},
+			want:  []RangeIssue{{Kind: IssueOutOfRange, ItemID: 2, Min: RollSpace, Max: RollSpace + 10}},
+		},
+		{
+			name:  "inverted",
+			items: []models.CaseItem{item(1, 0, top), item(2, 20, 10)},
+			want:  []RangeIssue{{Kind: IssueInverted, ItemID: 2, Min: 20, Max: 10}},
+		},
+		{
+			name:  "inverted leaves a gap",
+			items: []models.CaseItem{item(1, 0, 9), item(2, 20, 10), item(3, 21, top)},
+			want:  []RangeIssue{{Kind: IssueInverted, ItemID: 2, Min: 20, Max: 10}, {Kind: IssueGap, Min: 10, Max: 20}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckRanges(models.Case{ID: 1, Items: tt.items})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckRanges = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package provablyfair
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// RNG versions, recorded in Battle.PFair["rng"].
+const (
+	// RNGSha256V1 - FairRand: first 4 bytes of SHA-256(serverSeed:clientSeed:nonce) modulo max.
+	// Kept so battles rolled with it stay verifiable.
+	RNGSha256V1 = 1
+	// RNGHmacV2 - FairRandV2: HMAC-SHA256 keyed by the server seed with rejection sampling.
+	RNGHmacV2 = 2
+)
+
+// DefaultRNG is used by new battles that do not ask for a version.
+const DefaultRNG = RNGHmacV2
+
+// RandFunc returns a number in [0, max) for a seed pair and nonce.
+type RandFunc func(serverSeed, clientSeed string, nonce, max int) int
+
+// RNGs - all published generators by version
+var RNGs = map[int]RandFunc{
+	RNGSha256V1: FairRand,
+	RNGHmacV2:   FairRandV2,
+}
+
+// RNG returns the generator of a version, falling back to v1 for unknown versions.
+func RNG(version int) RandFunc {
+	if fn, ok := RNGs[version]; ok {
+		return fn
+	}
+	return FairRand
+}
+
+// FairRandV2 generates an unbiased number in [0, max).
+//
+// Each digest HMAC-SHA256(key=serverSeed, msg="clientSeed:nonce:cursor") is read as eight
+// big-endian uint32 words. Words at or above the largest multiple of max are rejected so
+// every result is equally likely; when a digest runs out the cursor moves to the next one.
+func FairRandV2(serverSeed, clientSeed string, nonce, max int) int {
+	if max <= 1 {
+		return 0
+	}
+	const space = uint64(1) << 32
+	limit := space - space%uint64(max)
+	for cursor := 0; ; cursor++ {
+		mac := hmac.New(sha256.New, []byte(serverSeed))
+		_, _ = fmt.Fprintf(mac, "%s:%d:%d", clientSeed, nonce, cursor)
+		sum := mac.Sum(nil)
+		for i := 0; i+4 <= len(sum); i += 4 {
+			word := uint64(binary.BigEndian.Uint32(sum[i : i+4]))
+			if word < limit {
+				return int(word % uint64(max))
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package provablyfair
+
+import (
+	"testing"
+)
+
+func TestFairRandV2(t *testing.T) {
+	// Published vectors, computed outside Go from the documented HMAC construction
+	tests := []struct {
+		serverSeed, clientSeed string
+		nonce, max             int
+		want                   int
+	}{
+		{"server-seed", "client-seed", 0, 100, 5},
+		{"server-seed", "client-seed", 111, RollSpace, 948520},
+		{"server-seed", "client-seed", 112, RollSpace, 385126},
+		{"other", "client-seed", 111, RollSpace, 574079},
+		{"server-seed", "client-seed", 111, 1, 0},
+		{"server-seed", "client-seed", 111, 0, 0},
+		{"server-seed", "client-seed", 111, -5, 0},
+	}
+	for _, tt := range tests {
+		if got := FairRandV2(tt.serverSeed, tt.clientSeed, tt.nonce, tt.max); got != tt.want {
+			t.Errorf("FairRandV2(%q, %q, %d, %d) = %d, want %d", tt.serverSeed, tt.clientSeed, tt.nonce, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestFairRandV2Bounds(t *testing.T) {
+	for _, max := range []int{2, 3, 7, 100, RollSpace, 3 << 30} {
+		for nonce := 0; nonce < 2000; nonce++ {
+			if got := FairRandV2("server-seed", "client-seed", nonce, max); got < 0 || got >= max {
+				t.Fatalf("FairRandV2(nonce %d, max %d) = %d, out of [0, %d)", nonce, max, got, max)
+			}
+		}
+	}
+}
+
+func TestFairRandV2Uniform(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		buckets int
+		limit   float64 // chi-square at p = 0.001 for buckets-1 degrees of freedom
+	}{
+		{name: "die", max: 6, buckets: 6, limit: 20.52},
+		// A quarter of the words fall at or above 3<<30 and are rejected; plain modulo
+		// would put half of the results in the first third
+		{name: "rejecting a quarter", max: 3 << 30, buckets: 3, limit: 13.82},
+	}
+	const draws = 30000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := make([]int, tt.buckets)
+			for nonce := 0; nonce < draws; nonce++ {
+				r := FairRandV2("server-seed", "client-seed", nonce, tt.max)
+				counts[r/(tt.max/tt.buckets)]++
+			}
+			expected := float64(draws) / float64(tt.buckets)
+			var chi2 float64
+			for _, c := range counts {
+				d := float64(c) - expected
+				chi2 += d * d / expected
+			}
+			if chi2 > tt.limit {
+				t.Errorf("chi-square %.2f over %v, want at most %.2f", chi2, counts, tt.limit)
+			}
+		})
+	}
+}
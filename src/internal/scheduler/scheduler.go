@@ -0,0 +1,106 @@
+// Package scheduler paces battles with timers instead of sleeping goroutines.
+//
+// Config holds the delays between the steps of a battle; Scheduler runs a function
+// once its delay is over, keyed so a step can be replaced or canceled before it fires.
+package scheduler
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config is the pacing of a battle.
+type Config struct {
+	StartDelay   time.Duration // seats full → first round rolled
+	RoundDelay   time.Duration // one round revealed → the next one rolled, the time clients animate a case
+	ResolveDelay time.Duration // last round revealed → winner picked
+	Retention    time.Duration // archived battle kept on the live index
+}
+
+// DefaultConfig is the pacing the battles always had.
+func DefaultConfig() Config {
+	return Config{
+		StartDelay:   250 * time.Millisecond,
+		RoundDelay:   6 * time.Second,
+		ResolveDelay: 0,
+		Retention:    600 * time.Second,
+	}
+}
+
+// ConfigFromEnv reads ROLL_START_DELAY_MS, ROUND_REVEAL_MS, RESOLVE_DELAY_MS and
+// ARCHIVE_RETENTION_SECONDS, keeping the default of any unset or invalid one.
+func ConfigFromEnv() Config {
+	c := DefaultConfig()
+	c.StartDelay = envDuration("ROLL_START_DELAY_MS", time.Millisecond, c.StartDelay)
+	c.RoundDelay = envDuration("ROUND_REVEAL_MS", time.Millisecond, c.RoundDelay)
+	c.ResolveDelay = envDuration("RESOLVE_DELAY_MS", time.Millisecond, c.ResolveDelay)
+	c.Retention = envDuration("ARCHIVE_RETENTION_SECONDS", time.Second, c.Retention)
+	return c
+}
+
+func envDuration(key string, unit time.Duration, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("[scheduler] invalid %s %q, using %s", key, v, def)
+		return def
+	}
+	return time.Duration(n) * unit
+}
+
+// Scheduler runs keyed functions after a delay, each on its own timer.
+type Scheduler struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// New returns an empty scheduler.
+func New() *Scheduler {
+	return &Scheduler{timers: make(map[string]*time.Timer)}
+}
+
+// After runs fn once d is over and returns when it will run. A pending function
+// with the same key is replaced.
+func (s *Scheduler) After(key string, d time.Duration, fn func()) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.timers[key]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		if s.timers[key] == t {
+			delete(s.timers, key)
+		}
+		s.mu.Unlock()
+		fn()
+	})
+	s.timers[key] = t
+	return time.Now().Add(d)
+}
+
+// Cancel stops the pending function of a key; it reports whether one was pending.
+func (s *Scheduler) Cancel(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.timers[key]
+	if !ok {
+		return false
+	}
+	delete(s.timers, key)
+	return t.Stop()
+}
+
+// Pending returns how many functions are waiting.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.timers)
+}
@@ -0,0 +1,83 @@
+// Package server wires the G1 API: the services the handlers use and the /ws and /web routes.
+//
+// main loads the env, runs Setup once and serves Handler; TestBattleEndToEnd does the
+// same against stand-in Core and UM servers, so both run the exact same wiring.
+package server
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/configs"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/scheduler"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/store"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/web"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/ws"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Setup connects Core and UM, loads battles, bots and cases and starts the background loops.
+// It reads the env as it is, so the caller loads .env or sets variables first.
+func Setup() {
+	if os.Getenv("DEBUG") == "1" {
+		configs.Debug = true
+	}
+
+	handlers.Store = store.New(os.Getenv("BATTLE_STORE"))
+
+	// Core is optional with the memory store and fixtures
+	if address := os.Getenv("CORE_GRPC_ADDRESS"); address != "" {
+		log.Println("🌐 [main] Core gRPC: ", address)
+		grpcclient.Connect(address)
+		grpcclient.TestConnection()
+	} else {
+		log.Println("🌐 [main] Core gRPC: not configured")
+	}
+
+	// WebSocket events
+	ws.EmitEventLoop()
+
+	// UM endpoint, parsed once
+	umclient.Setup(umclient.ConfigFromEnv())
+
+	// Token checks and the profile cache; a revoked session leaves its sockets as guests
+	auth.Setup(auth.ConfigFromEnv())
+	auth.OnRevoke(ws.UnbindUser)
+
+	// Roll pacing, before any battle is resumed
+	handlers.Pacing = scheduler.ConfigFromEnv()
+
+	handlers.FillBattleIndex()
+	handlers.FillBots()
+	handlers.FillCaseImpact()
+
+	// Battles the previous process left mid-roll, mid-resolve or mid-payout
+	handlers.RecoverBattles()
+
+	// Failed payouts are retried in the background
+	reconcileEvery := 60
+	if v, err := strconv.Atoi(os.Getenv("PAYOUT_RECONCILE_SECONDS")); err == nil && v > 0 {
+		reconcileEvery = v
+	}
+	handlers.StartPayoutReconciler(time.Duration(reconcileEvery) * time.Second)
+}
+
+// Handler returns the WebSocket and HTTP routes.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", ws.HandleWebSocket)
+	mux.HandleFunc("/web", withAPIVersion(web.HandleHTTP))
+	return mux
+}
+
+func withAPIVersion(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", configs.Version)
+		h.ServeHTTP(w, r)
+	}
+}
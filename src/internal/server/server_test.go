@@ -0,0 +1,312 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/standin"
+	"github.com/gorilla/websocket"
+	"math"
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	appToken  = "integration-app"
+	coreToken = "integration-core"
+	umXKey    = "integration-x"
+	balance   = 100.0
+	caseID    = "1"
+	rounds    = 2
+	timeout   = 30 * time.Second
+)
+
+// player - a UM user the test logs in as
+type player struct {
+	token string
+	id    int64
+	name  string
+}
+
+var (
+	alice = player{token: "token-alice", id: 101, name: "Alice"}
+	bob   = player{token: "token-bob", id: 102, name: "Bob"}
+)
+
+// TestBattleEndToEnd runs the server against stand-in Core and UM servers and drives a battle
+// through its whole life over the WebSocket API.
+//
+// Core and UM are internal/standin, seeded with the case and bot fixtures and two players. The
+// server is wired with Setup exactly as main does, with fast roll pacing. One player creates a
+// 1v1 battle and watches its room, the other joins; the test waits for the rounds, the winner
+// and the payout, then for the battle to be archived in Core, and checks the UM ledger: one
+// entry fee per player, winnings paid once, no money made or lost. Last, the battle is verified
+// and replayed, and its final standings checked against the stored result.
+func TestBattleEndToEnd(t *testing.T) {
+	// Stand-ins
+	core := standin.NewCore(coreToken)
+	var cases map[int]models.Case
+	readJSON(t, "../../configs/fixtures/cases.json", &cases)
+	core.SeedCases(cases)
+	var bots []map[string]interface{}
+	readJSON(t, "../../configs/fixtures/bots.json", &bots)
+	core.SeedBots(bots)
+	coreLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	standin.ServeCore(core, coreLis)
+
+	um := standin.NewUM(appToken, umXKey)
+	um.AddUser(alice.token, alice.id, alice.name, balance)
+	um.AddUser(bob.token, bob.id, bob.name, balance)
+	umSrv := httptest.NewServer(um)
+	t.Cleanup(umSrv.Close)
+
+	// Server
+	env := map[string]string{
+		"BATTLE_STORE":              "core",
+		"CORE_GRPC_ADDRESS":         coreLis.Addr().String(),
+		"CORE_GRPC_TOKEN":           coreToken,
+		"API_UM":                    umSrv.URL + ", " + appToken + ", " + umXKey,
+		"APP_TOKEN":                 appToken,
+		"CASES_FIXTURE":             "",
+		"BOTS_FIXTURE":              "",
+		"ROLL_START_DELAY_MS":       "50",
+		"ROUND_REVEAL_MS":           "100",
+		"RESOLVE_DELAY_MS":          "0",
+		"ARCHIVE_RETENTION_SECONDS": "1",
+		"PAYOUT_RECONCILE_SECONDS":  "1",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	Setup()
+	srv := httptest.NewServer(Handler())
+	t.Cleanup(srv.Close)
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	// Create, watch and join
+	a := dial(t, wsURL)
+	b := dial(t, wsURL)
+	a.request("bind", map[string]interface{}{"token": alice.token})
+	b.request("bind", map[string]interface{}{"token": bob.token})
+
+	created := a.request("newBattle", map[string]interface{}{
+		"playerType": "1v1",
+		"cases":      []interface{}{map[string]interface{}{caseID: rounds}},
+	})
+	var battle models.BattleCreated
+	remarshal(created, &battle)
+	if battle.ID < 1 {
+		t.Fatalf("newBattle: no battle id in %v", created)
+	}
+	t.Logf("created battle %d, cost %.2f", battle.ID, battle.Cost)
+
+	a.request("subscribe", map[string]interface{}{"battleId": battle.ID})
+	b.request("join", map[string]interface{}{"battleId": battle.ID, "slotId": 2})
+
+	// Roll, resolve, pay
+	for r := 1; r <= rounds; r++ {
+		a.await("battle.roundResult", battle.ID)
+	}
+	a.await("battle.resolved", battle.ID)
+	paid := a.await("battle.paid", battle.ID)
+	t.Logf("paid %v to user %v", paid["amount"], paid["userId"])
+
+	// Archive
+	deadline := time.Now().Add(timeout)
+	var game string
+	for {
+		g, live, ok := core.Game(battle.ID)
+		if !ok {
+			t.Fatalf("battle %d is not in Core", battle.ID)
+		}
+		if !live {
+			game = g
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("battle %d still live after %s", battle.ID, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Ledger
+	var stored models.Battle
+	if err := json.Unmarshal([]byte(game), &stored); err != nil {
+		t.Fatalf("stored game: %v", err)
+	}
+	won := make(map[int64]float64)
+	for key, p := range stored.Payouts {
+		if p.State != models.PayoutPaid {
+			t.Errorf("payout %s is %s", key, p.State)
+		}
+		won[int64(p.UserID)] += p.Amount
+	}
+	debits := make(map[int64]int)
+	credits := make(map[int64]float64)
+	for _, tx := range um.Transactions() {
+		switch tx.Type {
+		case "game_loss":
+			debits[int64(tx.UserID)]++
+			if !near(tx.Amount, battle.Cost) {
+				t.Errorf("user %d paid %.2f to enter, cost is %.2f", tx.UserID, tx.Amount, battle.Cost)
+			}
+		case "game_win":
+			credits[int64(tx.UserID)] += tx.Amount
+		}
+	}
+	for _, p := range []player{alice, bob} {
+		if debits[p.id] != 1 {
+			t.Errorf("%s was charged %d times", p.name, debits[p.id])
+		}
+		if !near(credits[p.id], won[p.id]) {
+			t.Errorf("%s was credited %.2f, won %.2f", p.name, credits[p.id], won[p.id])
+		}
+		if want := balance - battle.Cost + won[p.id]; !near(um.Balance(p.id), want) {
+			t.Errorf("%s has %.2f, expected %.2f", p.name, um.Balance(p.id), want)
+		}
+	}
+
+	// Verify
+	var verified models.VerifyResult
+	remarshal(b.request("verifyBattle", map[string]interface{}{"battleId": battle.ID}), &verified)
+	if !verified.Verified || !verified.CasesPinned || len(verified.Steps) != 2*rounds {
+		t.Errorf("verifyBattle: verified %v, cases pinned %v, %d steps", verified.Verified, verified.CasesPinned, len(verified.Steps))
+	}
+
+	// Replay
+	b.request("replayBattle", map[string]interface{}{"battleId": battle.ID, "speed": 10})
+	replayID := b.reqID
+	var last models.ReplayRound
+	for r := 1; r <= rounds; r++ {
+		remarshal(b.reply(replayID, "replay.round"), &last)
+		if last.Round != r {
+			t.Fatalf("replay sent round %d, expected %d", last.Round, r)
+		}
+	}
+	for slot, prize := range stored.Summery.Prizes {
+		if !near(last.SlotTotals[slot], prize) {
+			t.Errorf("replay ends with %s at %.2f, won %.2f", slot, last.SlotTotals[slot], prize)
+		}
+	}
+	var result models.ReplayResult
+	remarshal(b.reply(replayID, "replay.resolved"), &result)
+	if result.JackpotWinner != stored.Summery.JackpotWinner || len(result.Winners.Slots) != len(stored.Summery.Winners.Slots) {
+		t.Errorf("replay resolved to %v, stored %v", result.Winners.Slots, stored.Summery.Winners.Slots)
+	}
+}
+
+// client - a WebSocket connection and everything it received
+type client struct {
+	t     *testing.T
+	conn  *websocket.Conn
+	in    chan models.ReqRes
+	reqID int64
+}
+
+// dial connects, sends the app token and reads the handshake.
+func dial(t *testing.T, url string) *client {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	c := &client{t: t, conn: conn, in: make(chan models.ReqRes, 1000)}
+	go func() {
+		defer close(c.in)
+		for {
+			var msg models.ReqRes
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			c.in <- msg
+		}
+	}()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(appToken)); err != nil {
+		t.Fatalf("app token: %v", err)
+	}
+	c.next(func(m models.ReqRes) bool { return m.Type == "handshake" }, "handshake")
+	return c
+}
+
+// request sends a request and returns the data of its answer, failing on an error answer.
+func (c *client) request(reqType string, data map[string]interface{}) map[string]interface{} {
+	c.t.Helper()
+	c.reqID++
+	id := c.reqID
+	if err := c.conn.WriteJSON(models.Request{Type: reqType, ReqID: id, Data: data}); err != nil {
+		c.t.Fatalf("%s: %v", reqType, err)
+	}
+	msg := c.next(func(m models.ReqRes) bool { return m.ReqID == id }, reqType)
+	if msg.Status != 1 {
+		c.t.Fatalf("%s: %s (%d) %v", reqType, msg.Type, msg.Error, msg.Data)
+	}
+	out, _ := msg.Data.(map[string]interface{})
+	return out
+}
+
+// reply returns the data of the next frame of a request with a type, for requests answered more than once.
+func (c *client) reply(reqID int64, resType string) map[string]interface{} {
+	c.t.Helper()
+	msg := c.next(func(m models.ReqRes) bool { return m.ReqID == reqID && m.Type == resType }, resType)
+	out, _ := msg.Data.(map[string]interface{})
+	return out
+}
+
+// await returns the data of the next event of a type about a battle.
+func (c *client) await(eventType string, battleID int) map[string]interface{} {
+	c.t.Helper()
+	msg := c.next(func(m models.ReqRes) bool {
+		data, _ := m.Data.(map[string]interface{})
+		id, _ := data["battleId"].(float64)
+		return m.Type == eventType && int(id) == battleID
+	}, eventType)
+	out, _ := msg.Data.(map[string]interface{})
+	return out
+}
+
+// next skips messages until match, or fails after the timeout.
+func (c *client) next(match func(models.ReqRes) bool, what string) models.ReqRes {
+	c.t.Helper()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-c.in:
+			if !ok {
+				c.t.Fatalf("%s: connection closed", what)
+			}
+			if match(msg) {
+				return msg
+			}
+		case <-timer.C:
+			c.t.Fatalf("%s: nothing after %s", what, timeout)
+		}
+	}
+}
+
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+func remarshal(in interface{}, out interface{}) {
+	raw, _ := json.Marshal(in)
+	_ = json.Unmarshal(raw, out)
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
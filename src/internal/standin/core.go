@@ -0,0 +1,453 @@
+// Package standin has in-process stand-ins for the Core gRPC DataService and the UM API,
+// so the server can run, and be driven end to end, without either of them.
+//
+// The Core stand-in keeps its tables in memory and answers the statements G1 sends, matched
+// by their text, in the shape the real Core does: rows and count for reads, inserted_id and
+// rows_affected for writes. Any other statement is an error, so a new query shows up at once.
+package standin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	pb "github.com/Milad-Abooali/4in-cs2skin-g1/src/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// row is one table row, column → value as Core sends it.
+type row map[string]interface{}
+
+// statement - a query Core understands, by the start of its normalized text
+type statement struct {
+	prefix string
+	run    func(c *Core, args []interface{}) (*structpb.Struct, error)
+}
+
+// statements - checked in order, so a longer prefix goes before a shorter one it starts with
+var statements = []statement{
+	{"select version()", (*Core).version},
+
+	{"insert into g1_games", (*Core).insertGame},
+	{"update g1_games set game = ? where id = ?", (*Core).updateGame},
+	{"update g1_games set is_live = 0 where id = ?", (*Core).archiveGame},
+	{"update g1_games set income=?, expense=?, roi=?, he=? where id=?", (*Core).saveHE},
+	{"select game from g1_games where is_live=1", (*Core).liveGames},
+	{"select game from g1_games where id = ?", (*Core).game},
+	{"select game from g1_games where is_live = 0 and (json_search", (*Core).unpaidGames},
+	{"select avg(cb.he) as avg_he from g1_games", (*Core).avgHE},
+
+	{"select client_seed, server_seed, server_seed_hash", (*Core).userSeed},
+	{"insert into g1_user_seeds", (*Core).saveUserSeed},
+
+	{"select response from g1_idempotency", (*Core).idempotentResult},
+	{"insert ignore into g1_idempotency", (*Core).saveIdempotentResult},
+
+	{"select * from bots", (*Core).bots},
+	{"select id,name,color,price,distribution,rarity,weight from cases", (*Core).cases},
+	{"select ci.id, ci.case_id, ci.item_id", (*Core).caseItems},
+}
+
+// Core answers DataService.Query from memory.
+type Core struct {
+	pb.UnimplementedDataServiceServer
+
+	token string
+
+	mu       sync.Mutex
+	games    []row // by id, ids start at 1
+	seeds    map[int64]row
+	idem     map[string]row
+	botRows  []row
+	caseRows []row
+	itemRows []row
+}
+
+// NewCore returns an empty Core that accepts token, or any token when it is "".
+func NewCore(token string) *Core {
+	return &Core{
+		token: token,
+		seeds: make(map[int64]row),
+		idem:  make(map[string]row),
+	}
+}
+
+// ServeCore serves c on lis until the returned server is stopped.
+func ServeCore(c *Core, lis net.Listener) *grpc.Server {
+	s := grpc.NewServer()
+	pb.RegisterDataServiceServer(s, c)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Printf("[standin] core: %v", err)
+		}
+	}()
+	return s
+}
+
+// SeedCases fills the cases, case_items and g1_items tables from cases in the getCases shape.
+func (c *Core) SeedCases(cases map[int]models.Case) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.caseRows, c.itemRows = nil, nil
+	for _, cs := range cases {
+		c.caseRows = append(c.caseRows, row{
+			"id":           cs.ID,
+			"name":         cs.Name,
+			"color":        cs.Color,
+			"price":        cs.Price.String(),
+			"distribution": cs.Distribution,
+			"rarity":       cs.Rarity,
+			"weight":       cs.Weight,
+		})
+		for _, it := range cs.Items {
+			c.itemRows = append(c.itemRows, row{
+				"id":               it.ID,
+				"case_id":          cs.ID,
+				"item_id":          it.ItemID,
+				"min_rand":         it.MinRand,
+				"max_rand":         it.MaxRand,
+				"price":            it.Price.String(),
+				"rarity":           it.Rarity,
+				"color":            it.Color,
+				"market_hash_name": it.MarketHashName,
+				"category":         it.Category,
+				"wear":             it.Wear,
+			})
+		}
+	}
+	sort.Slice(c.caseRows, func(i, j int) bool { return c.caseRows[i]["id"].(int) < c.caseRows[j]["id"].(int) })
+}
+
+// SeedBots fills the bots table.
+func (c *Core) SeedBots(bots []map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.botRows = nil
+	for _, b := range bots {
+		c.botRows = append(c.botRows, row(b))
+	}
+}
+
+// Game returns the stored game JSON of a battle and whether it is still live.
+func (c *Core) Game(id int) (game string, live bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id < 1 || id > len(c.games) {
+		return "", false, false
+	}
+	r := c.games[id-1]
+	return r["game"].(string), r["is_live"] == 1, true
+}
+
+// Query - DataService
+func (c *Core) Query(_ context.Context, req *pb.QueryRequest) (*pb.QueryResponse, error) {
+	if c.token != "" && req.GetToken() != c.token {
+		return &pb.QueryResponse{Status: "error", Error: "invalid token"}, nil
+	}
+	args, err := paramValues(req.GetParams())
+	if err != nil {
+		return &pb.QueryResponse{Status: "error", Error: err.Error()}, nil
+	}
+
+	query := normalize(req.GetQuery())
+	for _, st := range statements {
+		if !strings.HasPrefix(query, st.prefix) {
+			continue
+		}
+		c.mu.Lock()
+		data, err := st.run(c, args)
+		c.mu.Unlock()
+		if err != nil {
+			return &pb.QueryResponse{Status: "error", Error: err.Error()}, nil
+		}
+		return &pb.QueryResponse{Status: "ok", Data: data}, nil
+	}
+	log.Printf("[standin] core: unsupported query %q", query)
+	return &pb.QueryResponse{Status: "error", Error: "unsupported query"}, nil
+}
+
+// normalize - lower case, single spaces, no trailing semicolon
+func normalize(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return strings.TrimSuffix(query, ";")
+}
+
+// paramValues - the Go values of typed query params
+func paramValues(params []*pb.QueryParam) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(params))
+	for i, p := range params {
+		switch v := p.GetValue().(type) {
+		case *pb.QueryParam_StringValue:
+			args = append(args, v.StringValue)
+		case *pb.QueryParam_IntValue:
+			args = append(args, v.IntValue)
+		case *pb.QueryParam_DoubleValue:
+			args = append(args, v.DoubleValue)
+		case *pb.QueryParam_BoolValue:
+			args = append(args, v.BoolValue)
+		case *pb.QueryParam_BytesValue:
+			args = append(args, string(v.BytesValue))
+		case *pb.QueryParam_NullValue:
+			args = append(args, nil)
+		default:
+			return nil, fmt.Errorf("param %d: no value", i+1)
+		}
+	}
+	return args, nil
+}
+
+// want - checks the number of params of a statement
+func want(args []interface{}, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expected %d params, got %d", n, len(args))
+	}
+	return nil
+}
+
+// rowsResult - the answer of a read
+func rowsResult(rows []row) (*structpb.Struct, error) {
+	list := make([]interface{}, 0, len(rows))
+	for _, r := range rows {
+		list = append(list, map[string]interface{}(r))
+	}
+	return structpb.NewStruct(map[string]interface{}{
+		"count": len(rows),
+		"rows":  list,
+	})
+}
+
+// execResult - the answer of a write
+func execResult(affected int, insertedID int) (*structpb.Struct, error) {
+	return structpb.NewStruct(map[string]interface{}{
+		"rows_affected": affected,
+		"inserted_id":   insertedID,
+	})
+}
+
+func (c *Core) version(_ []interface{}) (*structpb.Struct, error) {
+	return rowsResult([]row{{"version()": "standin"}})
+}
+
+// gameRow - the g1_games row of an id param, nil when there is none
+func (c *Core) gameRow(id interface{}) row {
+	n, ok := id.(int64)
+	if !ok || n < 1 || int(n) > len(c.games) {
+		return nil
+	}
+	return c.games[n-1]
+}
+
+func (c *Core) insertGame(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 3); err != nil {
+		return nil, err
+	}
+	c.games = append(c.games, row{
+		"id":               len(c.games) + 1,
+		"server_seed":      args[0],
+		"server_seed_hash": args[1],
+		"game":             args[2],
+		"is_live":          1,
+		"income":           0.0,
+		"expense":          0.0,
+		"roi":              0.0,
+		"he":               0.0,
+		"created_at":       time.Now().UTC().Format("2006-01-02 15:04:05"),
+	})
+	return execResult(1, len(c.games))
+}
+
+func (c *Core) updateGame(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 2); err != nil {
+		return nil, err
+	}
+	r := c.gameRow(args[1])
+	if r == nil {
+		return execResult(0, 0)
+	}
+	r["game"] = args[0]
+	return execResult(1, 0)
+}
+
+func (c *Core) archiveGame(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 1); err != nil {
+		return nil, err
+	}
+	r := c.gameRow(args[0])
+	if r == nil {
+		return execResult(0, 0)
+	}
+	r["is_live"] = 0
+	return execResult(1, 0)
+}
+
+func (c *Core) saveHE(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 5); err != nil {
+		return nil, err
+	}
+	r := c.gameRow(args[4])
+	if r == nil {
+		return execResult(0, 0)
+	}
+	r["income"], r["expense"], r["roi"], r["he"] = args[0], args[1], args[2], args[3]
+	return execResult(1, 0)
+}
+
+func (c *Core) liveGames(_ []interface{}) (*structpb.Struct, error) {
+	var rows []row
+	for _, r := range c.games {
+		if r["is_live"] == 1 {
+			rows = append(rows, row{"game": r["game"]})
+		}
+	}
+	return rowsResult(rows)
+}
+
+func (c *Core) game(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 1); err != nil {
+		return nil, err
+	}
+	r := c.gameRow(args[0])
+	if r == nil {
+		return rowsResult(nil)
+	}
+	return rowsResult([]row{{"game": r["game"]}})
+}
+
+// unpaidGames - archived games with a failed or pending payout, the JSON_SEARCH of the real query
+func (c *Core) unpaidGames(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 1); err != nil {
+		return nil, err
+	}
+	limit, _ := args[0].(int64)
+	var rows []row
+	for _, r := range c.games {
+		if int64(len(rows)) >= limit {
+			break
+		}
+		if r["is_live"] == 1 {
+			continue
+		}
+		var game struct {
+			Payouts map[string]struct {
+				State string `json:"state"`
+			} `json:"payouts"`
+		}
+		if err := json.Unmarshal([]byte(r["game"].(string)), &game); err != nil {
+			continue
+		}
+		for _, p := range game.Payouts {
+			if p.State == "failed" || p.State == "pending" {
+				rows = append(rows, row{"game": r["game"]})
+				break
+			}
+		}
+	}
+	return rowsResult(rows)
+}
+
+// avgHE - the average HE of the last archived games with income, a DECIMAL string like MySQL's
+func (c *Core) avgHE(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 1); err != nil {
+		return nil, err
+	}
+	limit, _ := args[0].(int64)
+	var (
+		sum float64
+		n   int64
+	)
+	for i := len(c.games) - 1; i >= 0 && n < limit; i-- {
+		r := c.games[i]
+		income, _ := r["income"].(float64)
+		if r["is_live"] == 1 || income <= 0 {
+			continue
+		}
+		he, _ := r["he"].(float64)
+		sum += he
+		n++
+	}
+	if n == 0 {
+		return rowsResult([]row{{"avg_he": nil}})
+	}
+	return rowsResult([]row{{"avg_he": fmt.Sprintf("%.6f", sum/float64(n))}})
+}
+
+func (c *Core) userSeed(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 1); err != nil {
+		return nil, err
+	}
+	userID, _ := args[0].(int64)
+	r, ok := c.seeds[userID]
+	if !ok {
+		return rowsResult(nil)
+	}
+	return rowsResult([]row{r})
+}
+
+func (c *Core) saveUserSeed(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 8); err != nil {
+		return nil, err
+	}
+	userID, _ := args[0].(int64)
+	_, existed := c.seeds[userID]
+	c.seeds[userID] = row{
+		"client_seed":               args[1],
+		"server_seed":               args[2],
+		"server_seed_hash":          args[3],
+		"previous_client_seed":      args[4],
+		"previous_server_seed":      args[5],
+		"previous_server_seed_hash": args[6],
+		"rotated_at":                args[7],
+	}
+	if existed {
+		return execResult(2, 0) // MySQL counts an updated duplicate as two
+	}
+	return execResult(1, 0)
+}
+
+// idemKey - the unique key of g1_idempotency
+func idemKey(userID, route, key interface{}) string {
+	return fmt.Sprintf("%v|%v|%v", userID, route, key)
+}
+
+func (c *Core) idempotentResult(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 4); err != nil {
+		return nil, err
+	}
+	r, ok := c.idem[idemKey(args[0], args[1], args[2])]
+	after, _ := args[3].(string)
+	if !ok || r["created_at"].(string) <= after { // DATETIME strings order like the times
+		return rowsResult(nil)
+	}
+	return rowsResult([]row{{"response": r["response"]}})
+}
+
+func (c *Core) saveIdempotentResult(args []interface{}) (*structpb.Struct, error) {
+	if err := want(args, 5); err != nil {
+		return nil, err
+	}
+	k := idemKey(args[0], args[1], args[2])
+	if _, ok := c.idem[k]; ok {
+		return execResult(0, 0)
+	}
+	c.idem[k] = row{"response": args[3], "created_at": args[4]}
+	return execResult(1, 0)
+}
+
+func (c *Core) bots(_ []interface{}) (*structpb.Struct, error) {
+	return rowsResult(c.botRows)
+}
+
+func (c *Core) cases(_ []interface{}) (*structpb.Struct, error) {
+	return rowsResult(c.caseRows)
+}
+
+func (c *Core) caseItems(_ []interface{}) (*structpb.Struct, error) {
+	return rowsResult(c.itemRows)
+}
@@ -0,0 +1,268 @@
+package standin
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// umUser - a UM account
+type umUser struct {
+	id          int64
+	displayName string
+	balance     float64
+	xp          int
+}
+
+// Fault - how UM fails a call it was told to fail with FailNext
+type Fault int
+
+const (
+	FaultUnavailable Fault = iota + 1 // answers 503, nothing booked
+	FaultTimeout                      // nothing booked, no answer until the caller gives up
+	FaultLost                         // booked, the connection dropped before the answer
+)
+
+// UM answers the UM API requests G1 makes: xGetJWT, xGetUser, xAddTransaction and xAddXp.
+//
+// Users are added with AddUser and known by their token. A transaction is booked once per
+// type and reference, like the UM ledger, so a retried payout or refund is not paid twice.
+// FailNext makes calls fail the ways a real UM does, for tests of the callers' retries.
+type UM struct {
+	appToken string
+	xKey     string
+
+	mu     sync.Mutex
+	users  map[int64]*umUser
+	tokens map[string]int64
+	txs    []umclient.Transaction
+	booked map[string]bool    // type|referenceID
+	faults map[string][]Fault // request type → faults of its next calls
+}
+
+// NewUM returns a UM without users that accepts appToken and xKey.
+func NewUM(appToken, xKey string) *UM {
+	return &UM{
+		appToken: appToken,
+		xKey:     xKey,
+		users:    make(map[int64]*umUser),
+		tokens:   make(map[string]int64),
+		booked:   make(map[string]bool),
+		faults:   make(map[string][]Fault),
+	}
+}
+
+// FailNext makes the next n calls of a request type, such as xAddTransaction, fail with fault.
+func (u *UM) FailNext(reqType string, n int, fault Fault) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i := 0; i < n; i++ {
+		u.faults[reqType] = append(u.faults[reqType], fault)
+	}
+}
+
+// takeFault - the fault of the next call of a request type, if any
+func (u *UM) takeFault(reqType string) Fault {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	faults := u.faults[reqType]
+	if len(faults) == 0 {
+		return 0
+	}
+	u.faults[reqType] = faults[1:]
+	return faults[0]
+}
+
+// AddUser adds a user that token logs in as.
+func (u *UM) AddUser(token string, id int64, displayName string, balance float64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.users[id] = &umUser{id: id, displayName: displayName, balance: balance}
+	u.tokens[token] = id
+}
+
+// Balance returns the wallet balance of a user.
+func (u *UM) Balance(id int64) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if user, ok := u.users[id]; ok {
+		return user.balance
+	}
+	return 0
+}
+
+// Xp returns the XP of a user.
+func (u *UM) Xp(id int64) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if user, ok := u.users[id]; ok {
+		return user.xp
+	}
+	return 0
+}
+
+// Transactions returns the booked transactions, oldest first.
+func (u *UM) Transactions() []umclient.Transaction {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]umclient.Transaction(nil), u.txs...)
+}
+
+// umRequest - the body of every UM call
+type umRequest struct {
+	Type string `json:"type"`
+	Data struct {
+		XKey        string  `json:"X_KEY"`
+		Token       string  `json:"token"`
+		UserID      int64   `json:"userID"`
+		TxType      string  `json:"type"`
+		ReferenceID string  `json:"referenceID"`
+		Amount      float64 `json:"amount"`
+		TxRef       string  `json:"txRef"`
+		Description string  `json:"description"`
+		Reason      string  `json:"reason"`
+		CreatedBy   string  `json:"createdBy"`
+	} `json:"data"`
+}
+
+// umError - a refusal, in the UM envelope
+type umError struct {
+	code    int
+	errType string
+	data    interface{}
+}
+
+// ServeHTTP - UM API
+func (u *UM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if u.appToken != "" && r.Header.Get("Authorization") != "Bearer "+u.appToken {
+		writeUM(w, nil, &umError{code: 1001, errType: "INVALID_APP_TOKEN"})
+		return
+	}
+	var req umRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if u.xKey != "" && req.Data.XKey != u.xKey {
+		writeUM(w, nil, &umError{code: 1001, errType: "INVALID_APP_TOKEN"})
+		return
+	}
+
+	switch u.takeFault(req.Type) {
+	case FaultUnavailable:
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	case FaultTimeout:
+		<-r.Context().Done()
+		return
+	case FaultLost:
+		u.serve(httptest.NewRecorder(), req)
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				_ = conn.Close()
+			}
+		}
+		return
+	}
+	u.serve(w, req)
+}
+
+// serve - answers a checked request
+func (u *UM) serve(w http.ResponseWriter, req umRequest) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	switch req.Type {
+	case "xGetJWT":
+		id, ok := u.tokens[req.Data.Token]
+		if !ok {
+			writeUM(w, nil, &umError{code: 1032, errType: "TOKEN_NOT_FOUND"})
+			return
+		}
+		writeUM(w, u.profile(id), nil)
+	case "xGetUser":
+		if _, ok := u.users[req.Data.UserID]; !ok {
+			writeUM(w, nil, &umError{code: 1040, errType: "USER_NOT_FOUND"})
+			return
+		}
+		writeUM(w, u.profile(req.Data.UserID), nil)
+	case "xAddTransaction":
+		writeUM(w, nil, u.addTransaction(req))
+	case "xAddXp":
+		user, ok := u.users[req.Data.UserID]
+		if !ok {
+			writeUM(w, nil, &umError{code: 1040, errType: "USER_NOT_FOUND"})
+			return
+		}
+		user.xp += int(req.Data.Amount)
+		writeUM(w, nil, nil)
+	default:
+		writeUM(w, nil, &umError{code: 1010, errType: "UNKNOWN_ROUTE", data: map[string]interface{}{"type": req.Type}})
+	}
+}
+
+// profile - the profile answer of a user; caller holds mu
+func (u *UM) profile(id int64) map[string]interface{} {
+	user := u.users[id]
+	return map[string]interface{}{
+		"profile": map[string]interface{}{
+			"id":           user.id,
+			"display_name": user.displayName,
+			"balance":      fmt.Sprintf("%.2f", user.balance), // DECIMAL, as UM sends it
+		},
+	}
+}
+
+// addTransaction - books game_loss and game_win once per reference; caller holds mu
+func (u *UM) addTransaction(req umRequest) *umError {
+	d := req.Data
+	user, ok := u.users[d.UserID]
+	if !ok {
+		return &umError{code: 1040, errType: "USER_NOT_FOUND"}
+	}
+	key := d.TxType + "|" + d.ReferenceID
+	if u.booked[key] {
+		return nil
+	}
+	switch d.TxType {
+	case "game_loss":
+		if user.balance < d.Amount {
+			return &umError{code: 7001, errType: "INSUFFICIENT_BALANCE", data: map[string]interface{}{
+				"balance": user.balance,
+			}}
+		}
+		user.balance -= d.Amount
+	case "game_win":
+		user.balance += d.Amount
+	default:
+		return &umError{code: 5003, errType: "INVALID_TYPE_OR_FORMAT", data: map[string]interface{}{
+			"fieldName": "type",
+		}}
+	}
+	u.booked[key] = true
+	u.txs = append(u.txs, umclient.Transaction{
+		UserID:      int(d.UserID),
+		Type:        d.TxType,
+		ReferenceID: d.ReferenceID,
+		Amount:      d.Amount,
+		TxRef:       d.TxRef,
+		Description: d.Description,
+	})
+	return nil
+}
+
+// writeUM - the UM envelope of an answer or a refusal
+func writeUM(w http.ResponseWriter, data interface{}, e *umError) {
+	out := map[string]interface{}{"status": 1, "data": data}
+	if e != nil {
+		out = map[string]interface{}{"status": 0, "error": e.code, "type": e.errType, "data": e.data}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
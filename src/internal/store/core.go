@@ -0,0 +1,397 @@
+package store
+
+import (
+	"encoding/json"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/grpcclient"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gameTable holds one row per battle; the battle itself is the JSON "game" column.
+const gameTable = "g1_games"
+
+// Core stores battles in the Core service database over gRPC.
+type Core struct{}
+
+// NewCore returns the gRPC-Core store.
+func NewCore() *Core {
+	return &Core{}
+}
+
+// Create - Core Store
+func (Core) Create(b *models.Battle, serverSeed, serverSeedHash string) (int, models.HandlerError) {
+	battleJSON, errR := encodeGame(b)
+	if errR.Code > 0 {
+		return 0, errR
+	}
+
+	// Build query
+	query := `INSERT INTO ` + gameTable + ` (server_seed,server_seed_hash, game) 
+				VALUES (?, ?, ?)`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, serverSeed, serverSeedHash, string(battleJSON))
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "DB_DATA"
+		errR.Code = 1070
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return 0, errR
+	}
+
+	// Extract inserted_id from nested struct
+	dataDB := res.Data.GetFields()
+	id := int(dataDB["inserted_id"].GetNumberValue())
+	if id < 1 {
+		errR.Type = "DB_DATA"
+		errR.Code = 1070
+		return 0, errR
+	}
+	return id, errR
+}
+
+// Update - Core Store
+func (Core) Update(b *models.Battle) models.HandlerError {
+	battleJSON, errR := encodeGame(b)
+	if errR.Code > 0 {
+		return errR
+	}
+	// Build query
+	query := `Update ` + gameTable + ` SET game = ? WHERE id = ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, string(battleJSON), b.ID)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return errR
+	}
+
+	// DB result rows count
+	dataDB := res.Data.GetFields()
+	if dataDB["rows_affected"].GetNumberValue() == 0 {
+		errR.Type = "USER_NOT_FOUND"
+		errR.Code = 1035
+		return errR
+	}
+	return errR
+}
+
+// LoadLive - Core Store
+func (Core) LoadLive() ([]*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+
+	// Build query
+	query := `SELECT game FROM ` + gameTable + ` WHERE is_live=1`
+
+	// gRPC Call
+	res, err := grpcclient.SendQuery(query)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return nil, errR
+	}
+	// Extract gRPC struct
+	dataDB := res.Data.GetFields()
+	// DB result rows count
+	if dataDB["count"].GetNumberValue() == 0 {
+		errR.Type = "DB_DATA"
+		errR.Code = 1070
+		return nil, errR
+	}
+
+	var battles []*models.Battle
+	for _, row := range dataDB["rows"].GetListValue().GetValues() {
+		b, errD := decodeGame(row.GetStructValue().GetFields()["game"].GetStringValue())
+		if errD.Code > 0 {
+			log.Println("Failed to unmarshal battle:", errD.Type)
+			continue
+		}
+		battles = append(battles, b)
+	}
+	return battles, errR
+}
+
+// Load - Core Store
+func (Core) Load(id int) (*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+
+	// Build query
+	query := `SELECT game FROM ` + gameTable + ` WHERE id = ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, id)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return nil, errR
+	}
+
+	// Extract gRPC struct
+	dataDB := res.Data.GetFields()
+
+	// DB result rows count
+	rows := dataDB["rows"].GetListValue().GetValues()
+	if dataDB["count"].GetNumberValue() == 0 || len(rows) == 0 {
+		errR.Type = "Battle_NOT_FOUND"
+		errR.Code = 1035
+		return nil, errR
+	}
+
+	row := rows[0].GetStructValue()
+	if row == nil {
+		errR.Type = "BATTLE_ROW_EMPTY"
+		errR.Code = 1038
+		return nil, errR
+	}
+	return decodeGame(row.GetFields()["game"].GetStringValue())
+}
+
+// LoadUnpaid - Core Store
+func (Core) LoadUnpaid(limit int) ([]*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+
+	// Build query - payouts live in the game JSON, Unpaid below has the last word
+	query := `SELECT game FROM ` + gameTable + ` WHERE is_live = 0
+				AND (JSON_SEARCH(game, 'one', 'failed', NULL, '$.payouts.*.state') IS NOT NULL
+				OR JSON_SEARCH(game, 'one', 'pending', NULL, '$.payouts.*.state') IS NOT NULL)
+				ORDER BY id LIMIT ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, limit)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return nil, errR
+	}
+
+	var battles []*models.Battle
+	for _, row := range res.Data.GetFields()["rows"].GetListValue().GetValues() {
+		b, errD := decodeGame(row.GetStructValue().GetFields()["game"].GetStringValue())
+		if errD.Code > 0 {
+			log.Println("Failed to unmarshal battle:", errD.Type)
+			continue
+		}
+		if b.Unpaid() {
+			battles = append(battles, b)
+		}
+	}
+	return battles, errR
+}
+
+// MarkArchived - Core Store
+func (Core) MarkArchived(id int) models.HandlerError {
+	var errR models.HandlerError
+
+	// Build query
+	query := `Update ` + gameTable + ` SET is_live = 0 WHERE id = ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, id)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+		return errR
+	}
+
+	// DB result rows count
+	dataDB := res.Data.GetFields()
+	if dataDB["rows_affected"].GetNumberValue() == 0 {
+		errR.Type = "USER_NOT_FOUND"
+		errR.Code = 1035
+		return errR
+	}
+	return errR
+}
+
+// SaveHE - Core Store
+func (Core) SaveHE(id int, t *he.Tracker) models.HandlerError {
+	var errR models.HandlerError
+	t.Finalize()
+
+	query := `UPDATE ` + gameTable + ` SET income=?, expense=?, roi=?, he=? WHERE id=?`
+	log.Printf("HE stats of game %d: income=%.2f expense=%.2f roi=%.2f he=%.2f", id, t.Income, t.Expense, t.ROI, t.HE)
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(
+		query,
+		utils.RoundToTwoDigits(t.Income),
+		utils.RoundToTwoDigits(t.Expense),
+		utils.RoundToTwoDigits(t.ROI),
+		utils.RoundToTwoDigits(t.HE),
+		id,
+	)
+	if err != nil || res == nil || res.Status != "ok" {
+		errR.Type = "PROFILE_GRPC_ERROR"
+		errR.Code = 1033
+		if res != nil {
+			errR.Data = res.Error
+		}
+	}
+	return errR
+}
+
+// AvgHE - Core Store
+func (Core) AvgHE(limit int) (float64, bool) {
+	query := `SELECT AVG(cb.he) AS avg_he FROM ` + gameTable + ` cb JOIN ( SELECT id FROM ` + gameTable + ` WHERE is_live = 0 AND income > 0 ORDER BY created_at DESC LIMIT ? ) ids ON cb.id = ids.id`
+	res, err := grpcclient.SendQueryArgs(query, limit)
+
+	if err != nil || res == nil || res.Status != "ok" {
+		return 0, false
+	}
+	dataDB := res.Data.GetFields()
+	exist := dataDB["count"].GetNumberValue()
+	if exist == 0 {
+		return 0, false
+	}
+	sHE := dataDB["rows"].GetListValue().GetValues()[0].GetStructValue().GetFields()["avg_he"].GetStringValue()
+	HE, _ := strconv.ParseFloat(sHE, 64)
+	return utils.RoundToTwoDigits(HE), true
+}
+
+// LoadUserSeed - Core Store
+func (Core) LoadUserSeed(userID int) (*models.UserSeed, bool) {
+	// Build query
+	query := `SELECT client_seed, server_seed, server_seed_hash, previous_client_seed, previous_server_seed, previous_server_seed_hash, rotated_at
+				FROM g1_user_seeds WHERE user_id = ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, userID)
+	if err != nil || res == nil || res.Status != "ok" {
+		return nil, false
+	}
+	dataDB := res.Data.GetFields()
+	if dataDB["count"].GetNumberValue() == 0 {
+		return nil, false
+	}
+	rows := dataDB["rows"].GetListValue().GetValues()
+	if len(rows) == 0 {
+		return nil, false
+	}
+	fields := rows[0].GetStructValue().GetFields()
+
+	seed := &models.UserSeed{
+		UserID:                 userID,
+		ClientSeed:             fields["client_seed"].GetStringValue(),
+		ServerSeed:             fields["server_seed"].GetStringValue(),
+		ServerSeedHash:         fields["server_seed_hash"].GetStringValue(),
+		PreviousClientSeed:     fields["previous_client_seed"].GetStringValue(),
+		PreviousServerSeed:     fields["previous_server_seed"].GetStringValue(),
+		PreviousServerSeedHash: fields["previous_server_seed_hash"].GetStringValue(),
+	}
+	seed.RotatedAt, _ = time.Parse("2006-01-02 15:04:05", fields["rotated_at"].GetStringValue())
+	if seed.ServerSeed == "" || seed.ClientSeed == "" {
+		return nil, false
+	}
+	return seed, true
+}
+
+// SaveUserSeed - Core Store
+func (Core) SaveUserSeed(seed *models.UserSeed) bool {
+	// Build query
+	query := `INSERT INTO g1_user_seeds (user_id, client_seed, server_seed, server_seed_hash, previous_client_seed, previous_server_seed, previous_server_seed_hash, rotated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE client_seed = VALUES(client_seed), server_seed = VALUES(server_seed), server_seed_hash = VALUES(server_seed_hash),
+				previous_client_seed = VALUES(previous_client_seed), previous_server_seed = VALUES(previous_server_seed),
+				previous_server_seed_hash = VALUES(previous_server_seed_hash), rotated_at = VALUES(rotated_at)`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(
+		query,
+		seed.UserID,
+		seed.ClientSeed,
+		seed.ServerSeed,
+		seed.ServerSeedHash,
+		seed.PreviousClientSeed,
+		seed.PreviousServerSeed,
+		seed.PreviousServerSeedHash,
+		seed.RotatedAt,
+	)
+	return err == nil && res != nil && res.Status == "ok"
+}
+
+// LoadResult - Core Store
+func (Core) LoadResult(userID int, route, key string) (*models.HandlerOK, bool) {
+	// Build query
+	query := `SELECT response FROM g1_idempotency WHERE user_id = ? AND route = ? AND idem_key = ? AND created_at > ?`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, userID, route, key, time.Now().Add(-IdempotencyTTL))
+	if err != nil || res == nil || res.Status != "ok" {
+		return nil, false
+	}
+	dataDB := res.Data.GetFields()
+	rows := dataDB["rows"].GetListValue().GetValues()
+	if dataDB["count"].GetNumberValue() == 0 || len(rows) == 0 {
+		return nil, false
+	}
+
+	var stored models.HandlerOK
+	response := rows[0].GetStructValue().GetFields()["response"].GetStringValue()
+	if err := json.Unmarshal([]byte(response), &stored); err != nil {
+		log.Printf("[LoadResult] user %d %s %s: %v", userID, route, key, err)
+		return nil, false
+	}
+	return &stored, true
+}
+
+// SaveResult - Core Store
+func (Core) SaveResult(userID int, route, key string, result models.HandlerOK) bool {
+	response, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[SaveResult] user %d %s %s: %v", userID, route, key, err)
+		return false
+	}
+
+	// Build query - INSERT IGNORE keeps the first response of a key
+	query := `INSERT IGNORE INTO g1_idempotency (user_id, route, idem_key, response, created_at) VALUES (?, ?, ?, ?, ?)`
+
+	// gRPC Call
+	res, err := grpcclient.SendQueryArgs(query, userID, route, key, string(response), time.Now())
+	return err == nil && res != nil && res.Status == "ok"
+}
+
+// decodeGame reads the "game" column, which older rows hold as a quoted JSON string.
+func decodeGame(battleStr string) (*models.Battle, models.HandlerError) {
+	var (
+		errR   models.HandlerError
+		battle models.Battle
+	)
+
+	if !strings.HasPrefix(battleStr, "{") {
+		unquoted, err := strconv.Unquote(battleStr)
+		if err != nil {
+			errR.Type = "BATTLE_JSON_DECODE_ERROR"
+			errR.Code = 1037
+			return nil, errR
+		}
+		battleStr = unquoted
+	}
+	if err := json.Unmarshal([]byte(battleStr), &battle); err != nil {
+		errR.Type = "BATTLE_JSON_ERROR"
+		errR.Code = 1036
+		return nil, errR
+	}
+	return &battle, errR
+}
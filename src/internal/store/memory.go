@@ -0,0 +1,282 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryGame mirrors a g1_games row.
+type memoryGame struct {
+	game           []byte // battle JSON, so loads never share state with the caller
+	serverSeed     string
+	serverSeedHash string
+	live           bool
+	tracker        he.Tracker
+	archivedAt     int // archive order, newest is highest
+}
+
+// Memory keeps battles and seeds in process, for tests and local development without Core.
+type Memory struct {
+	mu       sync.Mutex
+	nextID   int
+	archives int
+	games    map[int]*memoryGame
+	seeds    map[int]models.UserSeed
+	results  map[string]memoryResult
+}
+
+// memoryResult mirrors a g1_idempotency row.
+type memoryResult struct {
+	response  []byte
+	createdAt time.Time
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		games:   make(map[int]*memoryGame),
+		seeds:   make(map[int]models.UserSeed),
+		results: make(map[string]memoryResult),
+	}
+}
+
+// Create - Memory Store
+func (m *Memory) Create(b *models.Battle, serverSeed, serverSeedHash string) (int, models.HandlerError) {
+	battleJSON, errR := encodeGame(b)
+	if errR.Code > 0 {
+		return 0, errR
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	m.games[m.nextID] = &memoryGame{
+		game:           battleJSON,
+		serverSeed:     serverSeed,
+		serverSeedHash: serverSeedHash,
+		live:           true,
+	}
+	return m.nextID, errR
+}
+
+// Update - Memory Store
+func (m *Memory) Update(b *models.Battle) models.HandlerError {
+	battleJSON, errR := encodeGame(b)
+	if errR.Code > 0 {
+		return errR
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[b.ID]
+	if !ok {
+		errR.Type = "USER_NOT_FOUND"
+		errR.Code = 1035
+		return errR
+	}
+	g.game = battleJSON
+	return errR
+}
+
+// LoadLive - Memory Store
+func (m *Memory) LoadLive() ([]*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var battles []*models.Battle
+	for _, g := range m.games {
+		if !g.live {
+			continue
+		}
+		b, errD := decodeGame(string(g.game))
+		if errD.Code > 0 {
+			continue
+		}
+		battles = append(battles, b)
+	}
+	if len(battles) == 0 {
+		errR.Type = "DB_DATA"
+		errR.Code = 1070
+	}
+	return battles, errR
+}
+
+// Load - Memory Store
+func (m *Memory) Load(id int) (*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[id]
+	if !ok {
+		errR.Type = "Battle_NOT_FOUND"
+		errR.Code = 1035
+		return nil, errR
+	}
+	return decodeGame(string(g.game))
+}
+
+// LoadUnpaid - Memory Store
+func (m *Memory) LoadUnpaid(limit int) ([]*models.Battle, models.HandlerError) {
+	var errR models.HandlerError
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]int, 0, len(m.games))
+	for id, g := range m.games {
+		if !g.live {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	var battles []*models.Battle
+	for _, id := range ids {
+		if len(battles) >= limit {
+			break
+		}
+		var b models.Battle
+		if err := json.Unmarshal(m.games[id].game, &b); err != nil {
+			continue
+		}
+		if b.Unpaid() {
+			battles = append(battles, &b)
+		}
+	}
+	return battles, errR
+}
+
+// MarkArchived - Memory Store
+func (m *Memory) MarkArchived(id int) models.HandlerError {
+	var errR models.HandlerError
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[id]
+	if !ok || !g.live {
+		errR.Type = "USER_NOT_FOUND"
+		errR.Code = 1035
+		return errR
+	}
+	g.live = false
+	m.archives++
+	g.archivedAt = m.archives
+	return errR
+}
+
+// SaveHE - Memory Store
+func (m *Memory) SaveHE(id int, t *he.Tracker) models.HandlerError {
+	var errR models.HandlerError
+	t.Finalize()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, ok := m.games[id]
+	if !ok {
+		errR.Type = "Battle_NOT_FOUND"
+		errR.Code = 1035
+		return errR
+	}
+	g.tracker = *t
+	return errR
+}
+
+// AvgHE - Memory Store
+func (m *Memory) AvgHE(limit int) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Newest archived battles with income first
+	var recent []*memoryGame
+	for _, g := range m.games {
+		if !g.live && g.tracker.Income > 0 {
+			recent = append(recent, g)
+		}
+	}
+	if len(recent) == 0 {
+		return 0, false
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].archivedAt > recent[j].archivedAt })
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+
+	var sum float64
+	for _, g := range recent {
+		sum += g.tracker.HE
+	}
+	return utils.RoundToTwoDigits(sum / float64(len(recent))), true
+}
+
+// LoadUserSeed - Memory Store
+func (m *Memory) LoadUserSeed(userID int) (*models.UserSeed, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seed, ok := m.seeds[userID]
+	if !ok {
+		return nil, false
+	}
+	return &seed, true
+}
+
+// SaveUserSeed - Memory Store
+func (m *Memory) SaveUserSeed(seed *models.UserSeed) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seeds[seed.UserID] = *seed
+	return true
+}
+
+// LoadResult - Memory Store
+func (m *Memory) LoadResult(userID int, route, key string) (*models.HandlerOK, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.results[resultKey(userID, route, key)]
+	if !ok || time.Since(stored.createdAt) > IdempotencyTTL {
+		return nil, false
+	}
+	var res models.HandlerOK
+	if err := json.Unmarshal(stored.response, &res); err != nil {
+		return nil, false
+	}
+	return &res, true
+}
+
+// SaveResult - Memory Store
+func (m *Memory) SaveResult(userID int, route, key string, res models.HandlerOK) bool {
+	response, err := json.Marshal(res)
+	if err != nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := resultKey(userID, route, key)
+	if stored, ok := m.results[k]; ok && time.Since(stored.createdAt) <= IdempotencyTTL {
+		return true
+	}
+	m.results[k] = memoryResult{response: response, createdAt: time.Now()}
+	return true
+}
+
+// resultKey - Store Helper
+func resultKey(userID int, route, key string) string {
+	return fmt.Sprintf("%d|%s|%s", userID, route, key)
+}
+
+// encodeGame - Store Helper
+func encodeGame(b *models.Battle) ([]byte, models.HandlerError) {
+	var errR models.HandlerError
+	battleJSON, err := json.Marshal(b)
+	if err != nil {
+		errR.Type = "json.Marshal(battle)"
+		errR.Code = 1027
+	}
+	return battleJSON, errR
+}
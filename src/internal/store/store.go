@@ -0,0 +1,73 @@
+package store
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/he"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"log"
+	"time"
+)
+
+// BattleStore persists battles and their HE stats.
+// Failures come back as a HandlerError with Code > 0, ready to return from a handler.
+type BattleStore interface {
+	// Create stores a new live battle and returns its id; new battles are saved pending.
+	Create(b *models.Battle, serverSeed, serverSeedHash string) (int, models.HandlerError)
+	// Update replaces the stored state of a battle.
+	Update(b *models.Battle) models.HandlerError
+	// LoadLive returns every battle not archived yet.
+	LoadLive() ([]*models.Battle, models.HandlerError)
+	// Load returns a battle, live or archived.
+	Load(id int) (*models.Battle, models.HandlerError)
+	// LoadUnpaid returns up to limit archived battles with a payout not paid yet.
+	LoadUnpaid(limit int) ([]*models.Battle, models.HandlerError)
+	// MarkArchived takes a battle off the live list.
+	MarkArchived(id int) models.HandlerError
+	// SaveHE finalizes and stores the income, expense, ROI and HE of a battle.
+	SaveHE(id int, t *he.Tracker) models.HandlerError
+	// AvgHE averages the HE of the last archived battles with income.
+	AvgHE(limit int) (float64, bool)
+}
+
+// SeedStore persists the seed pair of every user.
+type SeedStore interface {
+	LoadUserSeed(userID int) (*models.UserSeed, bool)
+	SaveUserSeed(seed *models.UserSeed) bool
+}
+
+// IdempotencyStore keeps the response of money-moving requests by their idempotency key,
+// so a retried request is answered with the original response instead of running again.
+type IdempotencyStore interface {
+	// LoadResult returns the response stored for a user's key on a route within IdempotencyTTL.
+	LoadResult(userID int, route, key string) (*models.HandlerOK, bool)
+	// SaveResult stores the response of a user's key on a route; the first response wins.
+	SaveResult(userID int, route, key string, res models.HandlerOK) bool
+}
+
+// IdempotencyTTL is how long a stored response is replayed.
+const IdempotencyTTL = 24 * time.Hour
+
+// Store is everything the handlers persist.
+type Store interface {
+	BattleStore
+	SeedStore
+	IdempotencyStore
+}
+
+// Store kinds, selected with BATTLE_STORE.
+const (
+	KindCore   = "core"
+	KindMemory = "memory"
+)
+
+// New returns the store of a kind; anything but "memory" is the Core store.
+func New(kind string) Store {
+	switch kind {
+	case KindMemory:
+		log.Println("Battle store: memory")
+		return NewMemory()
+	case KindCore, "":
+	default:
+		log.Printf("Unknown BATTLE_STORE %q, using core", kind)
+	}
+	return NewCore()
+}
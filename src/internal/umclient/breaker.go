@@ -0,0 +1,50 @@
+package umclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker opens after failures in a row and lets one try through once cooldown is over.
+type breaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	count     int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	if b.failures <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.count < b.failures {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true // half open, this try decides
+	return true
+}
+
+func (b *breaker) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count = 0
+	b.probing = false
+}
+
+func (b *breaker) failed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count++
+	b.probing = false
+	if b.count >= b.failures {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
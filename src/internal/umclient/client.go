@@ -0,0 +1,223 @@
+// Package umclient talks to the UM API: profiles, wallet transactions and XP.
+//
+// API_UM is parsed once into a Config. Every call runs under its own timeout; the
+// read-only ones (VerifyJWT, GetUser) are retried on transport errors and 5xx answers,
+// wallet and XP writes are not, their callers own the retries. A write that reached UM
+// but got no answer fails with ErrUncertain: it may be booked, and the caller reconciles.
+// A circuit breaker stops calling UM after repeated failures and answers ErrUnavailable
+// until it cools down.
+package umclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config is where UM is and how patiently it is called.
+type Config struct {
+	BaseURL  string
+	AppToken string
+	XKey     string
+
+	ReadTimeout  time.Duration // VerifyJWT, GetUser
+	WriteTimeout time.Duration // AddTransaction, AddXp
+	Retries      int           // extra tries of a read
+
+	BreakerFailures int           // failures in a row that open the breaker
+	BreakerCooldown time.Duration // how long it stays open
+}
+
+// DefaultConfig has no endpoint; ConfigFromEnv fills it from API_UM.
+func DefaultConfig() Config {
+	return Config{
+		ReadTimeout:     3 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		Retries:         2,
+		BreakerFailures: 5,
+		BreakerCooldown: 30 * time.Second,
+	}
+}
+
+// ConfigFromEnv reads API_UM, "baseURL, appToken, xKey".
+func ConfigFromEnv() Config {
+	c := DefaultConfig()
+	parts := strings.Split(os.Getenv("API_UM"), ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if len(parts) < 3 || parts[0] == "" {
+		log.Println("[umclient] API_UM is not \"baseURL, appToken, xKey\"")
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	c.BaseURL, c.AppToken, c.XKey = parts[0], parts[1], parts[2]
+	return c
+}
+
+// Client calls UM with one Config and one breaker.
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+}
+
+// New returns a client for c.
+func New(c Config) *Client {
+	return &Client{
+		cfg:     c,
+		http:    &http.Client{},
+		breaker: &breaker{failures: c.BreakerFailures, cooldown: c.BreakerCooldown},
+	}
+}
+
+var (
+	defaultMu sync.Mutex
+	def       *Client
+)
+
+// Setup sets the client the package functions use.
+func Setup(c Config) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	def = New(c)
+}
+
+// Default returns the client set by Setup, or one from the env on first use.
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if def == nil {
+		def = New(ConfigFromEnv())
+	}
+	return def
+}
+
+// ErrUnavailable is UM not answering, or the breaker open after it stopped answering.
+var ErrUnavailable = errors.New("UM unavailable")
+
+// ErrUncertain is a write sent to UM without an answer, a timeout or a dropped connection:
+// it may have been booked. Writes are booked once per reference, so sending it again tells.
+var ErrUncertain = errors.New("UM outcome unknown")
+
+// Error is an answer of UM refusing a call.
+type Error struct {
+	Code int
+	Type string
+	Data interface{}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Type, e.Code)
+}
+
+// envelope is every UM answer.
+type envelope struct {
+	Status int             `json:"status"`
+	Error  int             `json:"error"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// call posts one request and decodes data into out; retry is for calls safe to repeat.
+func (c *Client) call(ctx context.Context, reqType string, data interface{}, timeout time.Duration, retry bool, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"type": reqType, "data": data})
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", reqType, err)
+	}
+
+	tries := 1
+	if retry {
+		tries += c.cfg.Retries
+	}
+	wait := 100 * time.Millisecond
+	var lastErr error
+	for attempt := 1; attempt <= tries; attempt++ {
+		if !c.breaker.allow() {
+			return ErrUnavailable
+		}
+		env, err := c.post(ctx, body, timeout)
+		if err != nil {
+			c.breaker.failed()
+			lastErr = err
+			log.Printf("[umclient] %s try %d: %v", reqType, attempt, err)
+			if attempt < tries {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return ErrUnavailable
+				}
+				wait *= 2
+			}
+			continue
+		}
+		c.breaker.succeeded()
+
+		if env.Status != 1 {
+			e := &Error{Code: env.Error, Type: env.Type}
+			if len(env.Data) > 0 {
+				_ = json.Unmarshal(env.Data, &e.Data)
+			}
+			return e
+		}
+		if out != nil && len(env.Data) > 0 {
+			if err := json.Unmarshal(env.Data, out); err != nil {
+				return fmt.Errorf("%s data: %w", reqType, err)
+			}
+		}
+		return nil
+	}
+	if !retry && sent(lastErr) {
+		return fmt.Errorf("%w: %v", ErrUncertain, lastErr)
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+// sent reports whether a failed try may have reached UM; only a refused connection did not.
+func sent(err error) bool {
+	var opErr *net.OpError
+	return !(errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// post sends one try; transport errors, 5xx and unreadable answers are failures.
+func (c *Client) post(ctx context.Context, body []byte, timeout time.Duration) (*envelope, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.AppToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return &env, nil
+}
@@ -0,0 +1,142 @@
+package umclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"strconv"
+)
+
+// Profile is the user UM answers a token or an ID with.
+type Profile struct {
+	ID          int64   `json:"id"`
+	DisplayName string  `json:"display_name"`
+	Balance     float64 `json:"balance"`
+}
+
+// UnmarshalJSON takes the balance as UM sends it, a number or a numeric string.
+func (p *Profile) UnmarshalJSON(raw []byte) error {
+	var v struct {
+		ID          json.Number `json:"id"`
+		DisplayName string      `json:"display_name"`
+		Balance     interface{} `json:"balance"`
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return err
+	}
+	id, err := v.ID.Float64()
+	if err != nil {
+		return err
+	}
+	p.ID = int64(id)
+	p.DisplayName = v.DisplayName
+	switch b := v.Balance.(type) {
+	case float64:
+		p.Balance = b
+	case string:
+		p.Balance, _ = strconv.ParseFloat(b, 64)
+	}
+	return nil
+}
+
+// profileData - the data of a profile answer
+type profileData struct {
+	Profile *Profile `json:"profile"`
+}
+
+// Transaction is a wallet movement.
+type Transaction struct {
+	UserID      int     `json:"userID"`
+	Type        string  `json:"type"`        // game_loss / game_win
+	ReferenceID string  `json:"referenceID"` // battle, slot, user and attempt, see handlers.entryReference
+	Amount      float64 `json:"amount"`
+	TxRef       string  `json:"txRef"`
+	Description string  `json:"description"`
+}
+
+// VerifyJWT returns the profile of a user token.
+func (c *Client) VerifyJWT(ctx context.Context, token string) (*Profile, error) {
+	var out profileData
+	err := c.call(ctx, "xGetJWT", map[string]interface{}{
+		"X_KEY": c.cfg.XKey,
+		"token": token,
+	}, c.cfg.ReadTimeout, true, &out)
+	if err != nil {
+		return nil, err
+	}
+	if out.Profile == nil {
+		return nil, &Error{Code: 1040, Type: "USER_NOT_FOUND"}
+	}
+	return out.Profile, nil
+}
+
+// GetUser returns the profile of a user ID.
+func (c *Client) GetUser(ctx context.Context, userID int) (*Profile, error) {
+	var out profileData
+	err := c.call(ctx, "xGetUser", map[string]interface{}{
+		"X_KEY":  c.cfg.XKey,
+		"userID": userID,
+	}, c.cfg.ReadTimeout, true, &out)
+	if err != nil {
+		return nil, err
+	}
+	if out.Profile == nil {
+		return nil, &Error{Code: 1040, Type: "USER_NOT_FOUND"}
+	}
+	return out.Profile, nil
+}
+
+// AddTransaction books a wallet movement, once.
+func (c *Client) AddTransaction(ctx context.Context, tx Transaction) error {
+	return c.call(ctx, "xAddTransaction", map[string]interface{}{
+		"X_KEY":       c.cfg.XKey,
+		"userID":      tx.UserID,
+		"type":        tx.Type,
+		"referenceID": tx.ReferenceID,
+		"amount":      tx.Amount,
+		"txRef":       tx.TxRef,
+		"description": tx.Description,
+	}, c.cfg.WriteTimeout, false, nil)
+}
+
+// AddXp grants, or with a negative amount takes, XP once.
+func (c *Client) AddXp(ctx context.Context, userID, amount int, reason, createdBy string) error {
+	return c.call(ctx, "xAddXp", map[string]interface{}{
+		"X_KEY":     c.cfg.XKey,
+		"userID":    userID,
+		"amount":    amount,
+		"reason":    reason,
+		"createdBy": createdBy,
+	}, c.cfg.WriteTimeout, false, nil)
+}
+
+// VerifyJWT - Default().VerifyJWT
+func VerifyJWT(ctx context.Context, token string) (*Profile, error) {
+	return Default().VerifyJWT(ctx, token)
+}
+
+// GetUser - Default().GetUser
+func GetUser(ctx context.Context, userID int) (*Profile, error) {
+	return Default().GetUser(ctx, userID)
+}
+
+// AddTransaction - Default().AddTransaction
+func AddTransaction(ctx context.Context, tx Transaction) error {
+	return Default().AddTransaction(ctx, tx)
+}
+
+// AddXp - Default().AddXp
+func AddXp(ctx context.Context, userID, amount int, reason, createdBy string) error {
+	return Default().AddXp(ctx, userID, amount, reason, createdBy)
+}
+
+// AsHandlerError turns a call error into the error a handler returns: UM's own code when it
+// refused, UM_UNAVAILABLE when it could not be reached or its answer was lost.
+func AsHandlerError(err error) models.HandlerError {
+	var e *Error
+	if errors.As(err, &e) {
+		return models.HandlerError{Type: e.Type, Code: e.Code, Data: e.Data}
+	}
+	return models.HandlerError{Type: "UM_UNAVAILABLE", Code: 5020}
+}
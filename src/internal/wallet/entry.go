@@ -0,0 +1,195 @@
+// Package wallet takes battle entry fees from the UM wallet as a small saga.
+//
+// Reserve debits the fee, the caller then seats the player and persists the battle,
+// and finally either Commits the entry or Rolls it back. A rollback credits the fee
+// back with a compensating transaction, so a failure after the debit never keeps
+// the player's money without a seat; a refund UM refuses is retried in the background.
+//
+// A debit whose answer was lost (umclient.ErrUncertain) is sent again under the same
+// reference, which UM books once, until UM answers. When it still has not, Reserve
+// fails and the debit is settled in the background: refunded if it turns out booked.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"log"
+	"time"
+)
+
+// refundAttempts - tries of a compensating refund before it is reported as failed
+const refundAttempts = 3
+
+// refundBackoff - wait before the second refund try, doubled after each failure
+var refundBackoff = time.Second
+
+// reconcileAttempts - debits sent again at once when UM's answer to one was lost
+const reconcileAttempts = 2
+
+// settleAttempts - background tries to learn the outcome of a debit Reserve gave up on
+const settleAttempts = 5
+
+// Entry is an entry fee debited from a user's wallet, pending until Commit or Rollback.
+type Entry struct {
+	UserID    int
+	Amount    float64
+	Reference string
+	Xp        int
+
+	description string
+	xpAdded     bool
+	settled     bool
+}
+
+// Reserve debits the entry fee. Nothing is kept when it returns an error: a debit UM may
+// have booked without saying so is refunded in the background once UM answers.
+func Reserve(userID int, amount float64, reference, description string) (*Entry, models.HandlerError) {
+	e := &Entry{
+		UserID:      userID,
+		Amount:      amount,
+		Reference:   reference,
+		description: description,
+	}
+
+	err := e.debit()
+	uncertain := errors.Is(err, umclient.ErrUncertain)
+	for try := 0; uncertain && !answered(err) && try < reconcileAttempts; try++ {
+		err = e.debit()
+	}
+	if err != nil {
+		log.Printf("[wallet] debit user %d %.2f ref %s: %v", userID, amount, reference, err)
+		if uncertain && !answered(err) {
+			go e.settle()
+		}
+		return nil, umclient.AsHandlerError(err)
+	}
+	return e, models.HandlerError{}
+}
+
+// debit sends the entry fee once.
+func (e *Entry) debit() error {
+	err := umclient.AddTransaction(context.Background(), umclient.Transaction{
+		UserID:      e.UserID,
+		Type:        "game_loss",
+		ReferenceID: e.Reference,
+		Amount:      e.Amount,
+		Description: e.description,
+	})
+	if err != nil {
+		return err
+	}
+	auth.Forget(int64(e.UserID))
+	return nil
+}
+
+// settle keeps sending a debit Reserve gave up on until UM answers, and refunds it when it was booked.
+func (e *Entry) settle() {
+	wait := refundBackoff
+	for attempt := 1; attempt <= settleAttempts; attempt++ {
+		time.Sleep(wait)
+		wait *= 2
+		err := e.debit()
+		if err == nil {
+			log.Printf("[wallet] debit user %d %.2f ref %s was booked, refunding", e.UserID, e.Amount, e.Reference)
+			_ = e.Rollback("entry not confirmed", nil)
+			return
+		}
+		if answered(err) {
+			return
+		}
+		log.Printf("[wallet] settle debit user %d %.2f ref %s, try %d: %v", e.UserID, e.Amount, e.Reference, attempt, err)
+	}
+	log.Printf("[wallet] DEBIT UNSETTLED user %d %.2f ref %s", e.UserID, e.Amount, e.Reference)
+}
+
+// answered reports whether UM answered a call, booking it or refusing it.
+func answered(err error) bool {
+	var refused *umclient.Error
+	return err == nil || errors.As(err, &refused)
+}
+
+// AddXp grants the entry XP; Rollback takes it back.
+func (e *Entry) AddXp(amount int, reason string) models.HandlerError {
+	if err := umclient.AddXp(context.Background(), e.UserID, amount, reason, "G1"); err != nil {
+		log.Printf("[wallet] xp user %d %d: %v", e.UserID, amount, err)
+		return umclient.AsHandlerError(err)
+	}
+	e.Xp = amount
+	e.xpAdded = true
+	return models.HandlerError{}
+}
+
+// Commit keeps the fee; a later Rollback does nothing.
+func (e *Entry) Commit() {
+	e.settled = true
+}
+
+// Rollback refunds the fee and takes back the XP of an entry that was not committed.
+// The refund is tried once right away. When that fails it returns the error and keeps
+// retrying in the background with backoff, so a caller holding a battle lock is not kept
+// waiting; done, when not nil, is then called from there with the outcome of the last try.
+func (e *Entry) Rollback(reason string, done func(err error)) error {
+	if e.settled {
+		return nil
+	}
+	e.settled = true
+
+	if e.xpAdded {
+		if err := umclient.AddXp(context.Background(), e.UserID, -e.Xp, "Refund Battle", "G1"); err != nil {
+			log.Printf("[wallet] take back xp user %d %d: %v", e.UserID, e.Xp, err)
+		}
+	}
+
+	err := e.refund(reason)
+	if err == nil {
+		return nil
+	}
+	log.Printf("[wallet] refund user %d %.2f ref %s, try 1: %v", e.UserID, e.Amount, e.Reference, err)
+	go e.retryRefund(reason, done)
+	return err
+}
+
+// retryRefund makes the remaining refund tries, waiting refundBackoff before the first and
+// doubling it after each failure.
+func (e *Entry) retryRefund(reason string, done func(err error)) {
+	wait := refundBackoff
+	var lastErr error
+	for attempt := 2; attempt <= refundAttempts; attempt++ {
+		time.Sleep(wait)
+		wait *= 2
+		if lastErr = e.refund(reason); lastErr == nil {
+			break
+		}
+		log.Printf("[wallet] refund user %d %.2f ref %s, try %d: %v", e.UserID, e.Amount, e.Reference, attempt, lastErr)
+	}
+	if lastErr != nil {
+		log.Printf("[wallet] REFUND FAILED user %d %.2f ref %s (%s)", e.UserID, e.Amount, e.Reference, reason)
+	}
+	if done != nil {
+		done(lastErr)
+	}
+}
+
+// RefundReference is the UM reference of the refund of an entry fee, apart from the debit's.
+func RefundReference(reference string) string {
+	return reference + "-refund"
+}
+
+// refund credits the fee back once.
+func (e *Entry) refund(reason string) error {
+	err := umclient.AddTransaction(context.Background(), umclient.Transaction{
+		UserID:      e.UserID,
+		Type:        "game_win",
+		ReferenceID: RefundReference(e.Reference),
+		Amount:      e.Amount,
+		Description: "Refund: " + reason,
+	})
+	if err != nil {
+		return err
+	}
+	auth.Forget(int64(e.UserID))
+	return nil
+}
@@ -0,0 +1,185 @@
+package wallet
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/auth"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/standin"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/umclient"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	userID    = 7
+	fee       = 10.0
+	reference = "42-s2-u7-a0"
+)
+
+func TestMain(m *testing.M) {
+	// Background retries play out in milliseconds
+	refundBackoff = 10 * time.Millisecond
+	os.Exit(m.Run())
+}
+
+// setupUM points umclient at a stand-in UM holding one user with 100, with short write
+// timeouts so a call UM does not answer fails fast.
+func setupUM(t *testing.T) *standin.UM {
+	t.Helper()
+	um := standin.NewUM("test-app", "test-x")
+	um.AddUser("token-user", userID, "User", 100)
+	srv := httptest.NewServer(um)
+	t.Cleanup(srv.Close)
+	umclient.Setup(umclient.Config{
+		BaseURL:         srv.URL,
+		AppToken:        "test-app",
+		XKey:            "test-x",
+		ReadTimeout:     time.Second,
+		WriteTimeout:    200 * time.Millisecond,
+		BreakerFailures: 100,
+		BreakerCooldown: time.Second,
+	})
+	auth.Setup(auth.DefaultConfig())
+	return um
+}
+
+// ledger counts the user's debits and refunds under the test reference.
+func ledger(um *standin.UM) (debits, refunds int) {
+	for _, tx := range um.Transactions() {
+		switch {
+		case tx.Type == "game_loss" && tx.ReferenceID == reference:
+			debits++
+		case tx.Type == "game_win" && tx.ReferenceID == RefundReference(reference):
+			refunds++
+		}
+	}
+	return debits, refunds
+}
+
+// settled waits for the background work of a test to leave the balance and the ledger as expected.
+func settled(t *testing.T, um *standin.UM, balance float64, debits, refunds int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d, r := ledger(um)
+		if um.Balance(userID) == balance && d == debits && r == refunds {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("balance %.2f, %d debits, %d refunds; want %.2f, %d, %d", um.Balance(userID), d, r, balance, debits, refunds)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  int
+		fault   standin.Fault
+		amount  float64
+		errCode int
+		balance float64 // after background settling
+		debits  int
+		refunds int
+	}{
+		{name: "booked", amount: fee, balance: 90, debits: 1},
+		{name: "answer lost, sent again", faults: 1, fault: standin.FaultLost, amount: fee, balance: 90, debits: 1},
+		{name: "timeout, sent again", faults: 1, fault: standin.FaultTimeout, amount: fee, balance: 90, debits: 1},
+		{name: "5xx, sent again", faults: 1, fault: standin.FaultUnavailable, amount: fee, balance: 90, debits: 1},
+		{name: "answers lost, booked one refunded", faults: reconcileAttempts + 1, fault: standin.FaultLost, amount: fee, errCode: 5020, balance: 100, debits: 1, refunds: 1},
+		{name: "timeouts, settled in background", faults: reconcileAttempts + 1, fault: standin.FaultTimeout, amount: fee, errCode: 5020, balance: 100, debits: 1, refunds: 1},
+		{name: "refused", amount: 500, errCode: 7001, balance: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			um := setupUM(t)
+			um.FailNext("xAddTransaction", tt.faults, tt.fault)
+
+			entry, errR := Reserve(userID, tt.amount, reference, "Case Battle")
+			if errR.Code != tt.errCode {
+				t.Fatalf("reserve: %s (%d), want %d", errR.Type, errR.Code, tt.errCode)
+			}
+			if (entry != nil) != (tt.errCode == 0) {
+				t.Fatalf("entry %v with error %d", entry, errR.Code)
+			}
+
+			settled(t, um, tt.balance, tt.debits, tt.refunds)
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  int
+		fault   standin.Fault
+		inline  bool // refunded by the first try
+		done    bool // done called from the background
+		doneErr bool
+		balance float64
+		refunds int
+	}{
+		{name: "refunded", inline: true, balance: 100, refunds: 1},
+		{name: "5xx, retried", faults: 1, fault: standin.FaultUnavailable, done: true, balance: 100, refunds: 1},
+		{name: "answer lost, retried once booked", faults: 1, fault: standin.FaultLost, done: true, balance: 100, refunds: 1},
+		{name: "every try fails", faults: refundAttempts, fault: standin.FaultUnavailable, done: true, doneErr: true, balance: 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			um := setupUM(t)
+			entry, errR := Reserve(userID, fee, reference, "Case Battle")
+			if entry == nil {
+				t.Fatalf("reserve: %s (%d)", errR.Type, errR.Code)
+			}
+			if errR = entry.AddXp(15, "Join Battle"); errR.Code > 0 {
+				t.Fatalf("xp: %s (%d)", errR.Type, errR.Code)
+			}
+			um.FailNext("xAddTransaction", tt.faults, tt.fault)
+
+			outcome := make(chan error, 1)
+			err := entry.Rollback("test", func(err error) { outcome <- err })
+			if (err == nil) != tt.inline {
+				t.Fatalf("rollback: %v, inline %v", err, tt.inline)
+			}
+			if tt.done {
+				select {
+				case err := <-outcome:
+					if (err != nil) != tt.doneErr {
+						t.Errorf("done: %v", err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("done not called")
+				}
+			}
+
+			settled(t, um, tt.balance, 1, tt.refunds)
+			if xp := um.Xp(userID); xp != 0 {
+				t.Errorf("xp %d after rollback, want it taken back", xp)
+			}
+
+			// Settled: another rollback sends nothing
+			if err := entry.Rollback("again", nil); err != nil {
+				t.Errorf("second rollback: %v", err)
+			}
+			if _, refunds := ledger(um); refunds != tt.refunds {
+				t.Errorf("second rollback refunded again: %d", refunds)
+			}
+		})
+	}
+}
+
+func TestCommit(t *testing.T) {
+	um := setupUM(t)
+	entry, errR := Reserve(userID, fee, reference, "Case Battle")
+	if entry == nil {
+		t.Fatalf("reserve: %s (%d)", errR.Type, errR.Code)
+	}
+	entry.Commit()
+	if err := entry.Rollback("after commit", nil); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if debits, refunds := ledger(um); debits != 1 || refunds != 0 || um.Balance(userID) != 90 {
+		t.Errorf("%d debits, %d refunds, balance %.2f", debits, refunds, um.Balance(userID))
+	}
+}
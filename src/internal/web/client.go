@@ -32,14 +32,19 @@ var postRoutes = map[string]func(map[string]interface{}) (models.HandlerOK, mode
 	"updateBots": handlers.UpdateBots,
 
 	// Cases
-	"getCases":    handlers.GetCases,
-	"updateCases": handlers.UpdateCases,
+	"getCases":     handlers.GetCases,
+	"updateCases":  handlers.UpdateCases,
+	"getCaseOdds":  handlers.GetCaseOdds,
+	"getCaseAudit": handlers.GetCaseAudit,
 
 	// Battles
 	"getLiveBattles":      handlers.GetLiveBattles,
 	"getBattleHistory":    handlers.GetBattleHistory,
+	"getBattleReplay":     handlers.GetBattleReplay,
+	"getPayoutReport":     handlers.GetPayoutReport,
 	"getBattleAdmin":      handlers.GetBattleAdmin,
 	"getLiveBattlesAdmin": handlers.GetLiveBattlesAdmin,
+	"verifyBattle":        handlers.VerifyBattle,
 
 	// User Actions
 	"cancelBattle": handlers.CancelBattle,
@@ -49,6 +54,13 @@ var postRoutes = map[string]func(map[string]interface{}) (models.HandlerOK, mode
 	"clearSlot":    handlers.ClearSlot,
 	"join":         handlers.Join,
 	"changeSeat":   handlers.ChangeSeat,
+
+	// Sessions
+	"revokeSession": handlers.RevokeSession,
+
+	// Provably Fair
+	"getSeeds":   handlers.GetSeeds,
+	"rotateSeed": handlers.RotateSeed,
 }
 
 func HandleHTTP(w http.ResponseWriter, r *http.Request) {
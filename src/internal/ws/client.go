@@ -5,6 +5,7 @@ import (
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/configs"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/validate"
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
@@ -27,6 +28,35 @@ func dispatch(conn *websocket.Conn, reqId int64, fn func(map[string]interface{})
 	EmitServer(res.Type)
 }
 
+// Executes a subscribe/unsubscribe handler and moves the connection in or out of the room it names
+func dispatchRoom(conn *websocket.Conn, reqId int64, fn func(map[string]interface{}) (models.HandlerOK, models.HandlerError), req map[string]interface{}, move func(*websocket.Conn, string)) {
+	res, err := fn(req)
+	if err.Code > 0 {
+		handlers.SendWSError(conn, reqId, err.Type, err.Code, err.Data)
+		return
+	}
+	if data, ok := res.Data.(map[string]interface{}); ok {
+		if room, ok := data["room"].(string); ok {
+			move(conn, room)
+		}
+	}
+	handlers.SendWSResponse(conn, reqId, res.Type, res.Data)
+}
+
+// Verifies a bind token and attaches the connection to its user
+func bind(conn *websocket.Conn, reqId int64, req map[string]interface{}) {
+	res, err := handlers.Bind(req)
+	if err.Code > 0 {
+		handlers.SendWSError(conn, reqId, err.Type, err.Code, err.Data)
+		return
+	}
+	data := res.Data.(map[string]interface{})
+	token, _, _ := validate.RequireString(req, "token", false)
+	BindConn(conn, data["userId"].(int64), token)
+	data["at"] = time.Now().UTC().Format(time.RFC3339)
+	handlers.SendWSResponse(conn, reqId, res.Type, data)
+}
+
 // All WS routes mapped to handlers
 var wsRoutes = map[string]func(*websocket.Conn, map[string]interface{}, int64){
 	// Ping
@@ -49,6 +79,12 @@ var wsRoutes = map[string]func(*websocket.Conn, map[string]interface{}, int64){
 	"updateCases": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
 		dispatch(c, reqId, handlers.UpdateCases, d)
 	},
+	"getCaseOdds": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.GetCaseOdds, d)
+	},
+	"getCaseAudit": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.GetCaseAudit, d)
+	},
 
 	// Battles
 	"getLiveBattles": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
@@ -57,12 +93,21 @@ var wsRoutes = map[string]func(*websocket.Conn, map[string]interface{}, int64){
 	"getBattleHistory": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
 		dispatch(c, reqId, handlers.GetBattleHistory, d)
 	},
+	"getBattleReplay": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.GetBattleReplay, d)
+	},
 	"getBattleAdmin": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
 		dispatch(c, reqId, handlers.GetBattleAdmin, d)
 	},
+	"getPayoutReport": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.GetPayoutReport, d)
+	},
 	"getLiveBattlesAdmin": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
 		dispatch(c, reqId, handlers.GetLiveBattlesAdmin, d)
 	},
+	"verifyBattle": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.VerifyBattle, d)
+	},
 
 	// User Actions
 	"cancelBattle": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
@@ -86,6 +131,40 @@ var wsRoutes = map[string]func(*websocket.Conn, map[string]interface{}, int64){
 	"changeSeat": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
 		dispatch(c, reqId, handlers.ChangeSeat, d)
 	},
+
+	// Sessions
+	"revokeSession": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.RevokeSession, d)
+	},
+
+	// Rooms
+	"subscribe": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatchRoom(c, reqId, handlers.Subscribe, d, JoinRoom)
+	},
+	"unsubscribe": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatchRoom(c, reqId, handlers.Unsubscribe, d, LeaveRoom)
+	},
+	"resync": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.Resync, d)
+	},
+
+	// Replays
+	"replayBattle": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		replay(c, reqId, d)
+	},
+	"stopReplay": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		handlers.SendWSResponse(c, reqId, "replay.stopped", map[string]any{
+			"stopped": StopReplay(c),
+		})
+	},
+
+	// Provably Fair
+	"getSeeds": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.GetSeeds, d)
+	},
+	"rotateSeed": func(c *websocket.Conn, d map[string]interface{}, reqId int64) {
+		dispatch(c, reqId, handlers.RotateSeed, d)
+	},
 }
 
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -120,10 +199,10 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		"apiVersion": configs.Version,
 		"serverTime": time.Now().UTC().Format(time.RFC3339),
 	})
-	EmitToAnyEvent("heartbeat", handlers.ClientBattleIndex(handlers.BattleIndex))
+	EmitIndexEvent("heartbeat", handlers.ClientBattleIndex())
 
 	// Fill BattleIndex From DB
-	if len(handlers.BattleIndex) == 0 {
+	if handlers.BattleCount() == 0 {
 		handlers.FillBattleIndex()
 	}
 
@@ -148,14 +227,26 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			log.Println("Web Req:", msg.Type)
 		}
 
-		// Special case: bind
+		// Special case: bind / unbind - a token refresh is a bind with the new token
 		if msg.Type == "bind" {
-			handlers.SendWSResponse(conn, 1, "bind.ok", map[string]any{
+			bind(conn, msg.ReqID, reqData)
+			continue
+		}
+		if msg.Type == "unbind" {
+			UnbindConn(conn)
+			handlers.SendWSResponse(conn, msg.ReqID, "unbind.ok", map[string]any{
 				"at": time.Now().UTC().Format(time.RFC3339),
 			})
 			continue
 		}
 
+		// Bound connections don't re-send their token
+		if _, ok := reqData["token"]; !ok {
+			if token := BoundToken(conn); token != "" {
+				reqData["token"] = token
+			}
+		}
+
 		// Dispatch via map
 		if fn, found := wsRoutes[msg.Type]; found {
 			fn(conn, reqData, msg.ReqID)
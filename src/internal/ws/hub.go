@@ -1,9 +1,11 @@
 package ws
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/events"
 	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
 	"sync"
 	"time"
 
@@ -13,13 +15,21 @@ import (
 type connInfo struct {
 	Conn   *websocket.Conn
 	UserID int64
-	mu     sync.Mutex // serialize writes per-connection
+	token  string          // JWT the connection is bound with, guarded by regMu
+	rooms  map[string]bool // subscribed rooms, guarded by regMu
+	mu     *sync.Mutex     // serialize writes per-connection, shared with handlers.SendWS*
 }
 
 var (
 	regMu  sync.RWMutex
 	byConn = make(map[*websocket.Conn]*connInfo)
 	byUser = make(map[int64]map[*websocket.Conn]*connInfo)
+	byRoom = make(map[string]map[*websocket.Conn]*connInfo)
+)
+
+var (
+	snapshotMu sync.Mutex
+	snapshots  = make(map[int64][]byte) // last battle.snapshot sent to each watched battle room
 )
 
 // RegisterConn should be called right after upgrade
@@ -27,7 +37,7 @@ func RegisterConn(c *websocket.Conn) {
 	regMu.Lock()
 	defer regMu.Unlock()
 	if _, ok := byConn[c]; !ok {
-		byConn[c] = &connInfo{Conn: c, UserID: 0}
+		byConn[c] = &connInfo{Conn: c, UserID: 0, rooms: make(map[string]bool), mu: handlers.WSWriteLock(c)}
 	}
 }
 
@@ -40,15 +50,112 @@ func UnregisterConn(c *websocket.Conn) {
 		return
 	}
 	// remove from user bucket if bound
+	unbindUser(ci)
+	// remove from rooms
+	for room := range ci.rooms {
+		leaveRoom(ci, room)
+	}
+	delete(byConn, c)
+	StopReplay(c)
+	handlers.ReleaseWSWriteLock(c)
+}
+
+// BindConn attaches a registered connection to a user, moving it from any user it was bound to
+func BindConn(c *websocket.Conn, userID int64, token string) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	ci, ok := byConn[c]
+	if !ok {
+		return
+	}
+	unbindUser(ci)
+	ci.UserID = userID
+	ci.token = token
+	set, ok := byUser[userID]
+	if !ok {
+		set = make(map[*websocket.Conn]*connInfo)
+		byUser[userID] = set
+	}
+	set[c] = ci
+}
+
+// UnbindConn turns a connection back into a guest
+func UnbindConn(c *websocket.Conn) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	if ci, ok := byConn[c]; ok {
+		unbindUser(ci)
+	}
+}
+
+// UnbindUser turns every connection of a user back into a guest, after their session is revoked
+func UnbindUser(userID int64) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	for _, ci := range byUser[userID] {
+		unbindUser(ci)
+	}
+}
+
+// BoundToken returns the JWT a connection is bound with, "" for guests
+func BoundToken(c *websocket.Conn) string {
+	regMu.RLock()
+	defer regMu.RUnlock()
+	if ci, ok := byConn[c]; ok {
+		return ci.token
+	}
+	return ""
+}
+
+// internal helper: caller holds regMu
+func unbindUser(ci *connInfo) {
 	if ci.UserID != 0 {
 		if set, ok := byUser[ci.UserID]; ok {
-			delete(set, c)
+			delete(set, ci.Conn)
 			if len(set) == 0 {
 				delete(byUser, ci.UserID)
 			}
 		}
 	}
-	delete(byConn, c)
+	ci.UserID = 0
+	ci.token = ""
+}
+
+// JoinRoom subscribes a registered connection to a room
+func JoinRoom(c *websocket.Conn, room string) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	ci, ok := byConn[c]
+	if !ok {
+		return
+	}
+	set, ok := byRoom[room]
+	if !ok {
+		set = make(map[*websocket.Conn]*connInfo)
+		byRoom[room] = set
+	}
+	set[c] = ci
+	ci.rooms[room] = true
+}
+
+// LeaveRoom unsubscribes a connection from a room
+func LeaveRoom(c *websocket.Conn, room string) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	if ci, ok := byConn[c]; ok {
+		leaveRoom(ci, room)
+	}
+}
+
+// internal helper: caller holds regMu
+func leaveRoom(ci *connInfo, room string) {
+	delete(ci.rooms, room)
+	if set, ok := byRoom[room]; ok {
+		delete(set, ci.Conn)
+		if len(set) == 0 {
+			delete(byRoom, room)
+		}
+	}
 }
 
 // internal helper: send payload to a list of connections
@@ -118,6 +225,30 @@ func EmitToGuests(payload any) {
 	emitToTargets(targets, payload)
 }
 
+// EmitToRoom sends to the connections subscribed to a room
+func EmitToRoom(room string, payload any) {
+	regMu.RLock()
+	var targets []*connInfo
+	for _, ci := range byRoom[room] {
+		targets = append(targets, ci)
+	}
+	regMu.RUnlock()
+	emitToTargets(targets, payload)
+}
+
+// EmitToUnsubscribed sends to connections in no room, the clients that still read the full heartbeat
+func EmitToUnsubscribed(payload any) {
+	regMu.RLock()
+	var targets []*connInfo
+	for _, ci := range byConn {
+		if len(ci.rooms) == 0 {
+			targets = append(targets, ci)
+		}
+	}
+	regMu.RUnlock()
+	emitToTargets(targets, payload)
+}
+
 // === Event helpers ===
 
 func EmitToUserEvent(userID int64, eventType string, data any) {
@@ -152,6 +283,67 @@ func EmitToGuestsEvent(eventType string, data any) {
 	})
 }
 
+func EmitToRoomEvent(room string, eventType string, data any) {
+	EmitToRoom(room, map[string]any{
+		"type": eventType,
+		"data": data,
+		"at":   time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func EmitToUnsubscribedEvent(eventType string, data any) {
+	EmitToUnsubscribed(map[string]any{
+		"type": eventType,
+		"data": data,
+		"at":   time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// EmitIndexEvent spreads a battle index: the full index to unsubscribed connections, seq'd
+// deltas of the current index to the lobby and each changed battle to its own room.
+func EmitIndexEvent(eventType string, data any) {
+	index, ok := data.(map[int64]models.BattleClient)
+	if !ok {
+		EmitToAnyEvent(eventType, data)
+		return
+	}
+	EmitToUnsubscribedEvent(eventType, index)
+	handlers.LobbyFeed(func(d models.LobbyDelta) {
+		EmitToRoomEvent(handlers.LobbyRoom, d.Type, d)
+	})
+
+	emitSnapshots(index)
+}
+
+// emitSnapshots sends battle.snapshot to the watched rooms whose battle differs from what
+// the room was last sent. Sends happen under snapshotMu so a room never gets them out of order.
+func emitSnapshots(index map[int64]models.BattleClient) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+
+	for id := range snapshots {
+		if _, ok := index[id]; !ok {
+			delete(snapshots, id)
+		}
+	}
+	for id, b := range index {
+		room := handlers.BattleRoom(id)
+		regMu.RLock()
+		_, watched := byRoom[room]
+		regMu.RUnlock()
+		if !watched {
+			delete(snapshots, id)
+			continue
+		}
+		raw, err := json.Marshal(b)
+		if err != nil || bytes.Equal(raw, snapshots[id]) {
+			continue
+		}
+		snapshots[id] = raw
+		EmitToRoomEvent(room, "battle.snapshot", b)
+	}
+}
+
 func EmitServer(resType string) {
 
 	switch resType {
@@ -160,13 +352,22 @@ func EmitServer(resType string) {
 		"updateBots",
 		"getCases",
 		"updateCases",
+		"getCaseOdds",
+		"getCaseAudit",
 		"getLiveBattles",
 		"getBattleHistory",
-		"getBattleAdmin":
+		"getBattleReplay",
+		"getBattleAdmin",
+		"getPayoutReport",
+		"verifyBattle",
+		"getSeeds",
+		"rotateSeed",
+		"resync",
+		"revokeSession":
 		// No Emit
 
 	default:
-		EmitToAnyEvent("heartbeat", handlers.ClientBattleIndex(handlers.BattleIndex))
+		EmitIndexEvent("heartbeat", handlers.ClientBattleIndex())
 	}
 
 }
@@ -177,6 +378,10 @@ func EmitEventLoop() {
 			switch ev.Target {
 			case "all":
 				EmitToAnyEvent(ev.Type, ev.Data)
+			case "index":
+				EmitIndexEvent(ev.Type, ev.Data)
+			case "battle":
+				EmitToRoomEvent(handlers.BattleRoom(ev.BattleID), ev.Type, ev.Data)
 			case "user":
 				EmitToUserEvent(ev.UserID, ev.Type, ev.Data)
 			case "allUsers":
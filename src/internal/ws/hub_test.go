@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"encoding/json"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// watch connects a client to a hub connection subscribed to the room of a battle and
+// returns the statuses of the battle.snapshot messages it reads.
+func watch(t *testing.T, battleID int64) <-chan string {
+	t.Helper()
+	joined := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		RegisterConn(conn)
+		JoinRoom(conn, handlers.BattleRoom(battleID))
+		close(joined)
+		t.Cleanup(func() { UnregisterConn(conn) })
+	}))
+	t.Cleanup(srv.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	<-joined
+
+	statuses := make(chan string, 16)
+	go func() {
+		for {
+			_, raw, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg struct {
+				Type string              `json:"type"`
+				Data models.BattleClient `json:"data"`
+			}
+			if json.Unmarshal(raw, &msg) == nil && msg.Type == "battle.snapshot" {
+				statuses <- msg.Data.Status
+			} else {
+				statuses <- "unexpected " + string(raw)
+			}
+		}
+	}()
+	return statuses
+}
+
+// received drains the statuses read until the connection goes quiet.
+func received(statuses <-chan string) []string {
+	var out []string
+	for {
+		select {
+		case s := <-statuses:
+			out = append(out, s)
+		case <-time.After(100 * time.Millisecond):
+			return out
+		}
+	}
+}
+
+func TestEmitSnapshots(t *testing.T) {
+	watched := watch(t, 1)
+	index := func(status1, status2 string) map[int64]models.BattleClient {
+		return map[int64]models.BattleClient{
+			1: {ID: 1, Status: status1},
+			2: {ID: 2, Status: status2},
+		}
+	}
+
+	steps := []struct {
+		name  string
+		index map[int64]models.BattleClient
+		want  []string
+	}{
+		{"first sight", index("Waiting for 1 users", "Waiting for 1 users"), []string{"Waiting for 1 users"}},
+		{"nothing changed", index("Waiting for 1 users", "Waiting for 1 users"), nil},
+		{"other battle changed", index("Waiting for 1 users", "Start Rolling"), nil},
+		{"watched battle changed", index("Start Rolling", "Start Rolling"), []string{"Start Rolling"}},
+		{"off the index", map[int64]models.BattleClient{}, nil},
+		{"back on the index", index("Start Rolling", "Start Rolling"), []string{"Start Rolling"}},
+	}
+	for _, step := range steps {
+		emitSnapshots(step.index)
+		got := received(watched)
+		if strings.Join(got, ",") != strings.Join(step.want, ",") {
+			t.Errorf("%s: snapshots %q, want %q", step.name, got, step.want)
+		}
+	}
+
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	if _, kept := snapshots[2]; kept {
+		t.Errorf("snapshot of unwatched battle 2 kept")
+	}
+}
@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/handlers"
+	"github.com/Milad-Abooali/4in-cs2skin-g1/src/internal/models"
+	"github.com/gorilla/websocket"
+	"sync"
+	"time"
+)
+
+// One replay per connection; starting another, stopReplay or closing the connection ends it.
+var (
+	replayMu sync.Mutex
+	replays  = make(map[*websocket.Conn]chan struct{})
+)
+
+// Streams a finished battle to the connection: replay.started with the battle, a replay.round
+// per round RevealMs apart, then replay.resolved with the winners. Every frame carries reqId.
+func replay(conn *websocket.Conn, reqId int64, req map[string]interface{}) {
+	res, err := handlers.GetBattleReplay(req)
+	if err.Code > 0 {
+		handlers.SendWSError(conn, reqId, err.Type, err.Code, err.Data)
+		return
+	}
+	rp := res.Data.(models.BattleReplay)
+	rounds, result := rp.Rounds, rp.Result
+	rp.Rounds, rp.Result = nil, nil
+
+	stop := startReplay(conn)
+	handlers.SendWSResponse(conn, reqId, "replay.started", rp)
+
+	go func() {
+		defer endReplay(conn, stop)
+		reveal := time.Duration(rp.RevealMs) * time.Millisecond
+		for i, round := range rounds {
+			if i > 0 && !replayWait(reveal, stop) {
+				return
+			}
+			handlers.SendWSResponse(conn, reqId, "replay.round", round)
+		}
+		if !replayWait(reveal, stop) {
+			return
+		}
+		handlers.SendWSResponse(conn, reqId, "replay.resolved", result)
+	}()
+}
+
+// startReplay ends the running replay of a connection and registers a new one
+func startReplay(conn *websocket.Conn) chan struct{} {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if stop, ok := replays[conn]; ok {
+		close(stop)
+	}
+	stop := make(chan struct{})
+	replays[conn] = stop
+	return stop
+}
+
+// endReplay forgets a finished replay, unless a newer one took its place
+func endReplay(conn *websocket.Conn, stop chan struct{}) {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if replays[conn] == stop {
+		delete(replays, conn)
+	}
+}
+
+// StopReplay ends the running replay of a connection; it reports whether there was one
+func StopReplay(conn *websocket.Conn) bool {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	stop, ok := replays[conn]
+	if ok {
+		close(stop)
+		delete(replays, conn)
+	}
+	return ok
+}
+
+// replayWait sleeps d, or returns false as soon as the replay is stopped
+func replayWait(d time.Duration, stop chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
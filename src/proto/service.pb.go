@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.33.0
+// 	protoc-gen-go v1.36.6
 // 	protoc        v6.31.1
 // source: proto/service.proto
 
@@ -12,6 +12,7 @@ import (
 	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -22,21 +23,20 @@ const (
 )
 
 type QueryRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Token string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Query string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	// Values bound, in order, to the ? placeholders of query; never interpolated into the SQL text.
+	Params        []*QueryParam `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"`
 	unknownFields protoimpl.UnknownFields
-
-	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
-	Query string `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *QueryRequest) Reset() {
 	*x = QueryRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_service_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_proto_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *QueryRequest) String() string {
@@ -47,7 +47,7 @@ func (*QueryRequest) ProtoMessage() {}
 
 func (x *QueryRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_service_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -76,23 +76,174 @@ func (x *QueryRequest) GetQuery() string {
 	return ""
 }
 
-type QueryResponse struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
+func (x *QueryRequest) GetParams() []*QueryParam {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+// QueryParam is one typed positional parameter.
+type QueryParam struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*QueryParam_StringValue
+	//	*QueryParam_IntValue
+	//	*QueryParam_DoubleValue
+	//	*QueryParam_BoolValue
+	//	*QueryParam_BytesValue
+	//	*QueryParam_NullValue
+	Value         isQueryParam_Value `protobuf_oneof:"value"`
 	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-	Status string           `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
-	Error  string           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	Data   *structpb.Struct `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+func (x *QueryParam) Reset() {
+	*x = QueryParam{}
+	mi := &file_proto_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryResponse) Reset() {
-	*x = QueryResponse{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_service_proto_msgTypes[1]
+func (x *QueryParam) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryParam) ProtoMessage() {}
+
+func (x *QueryParam) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_service_proto_msgTypes[1]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryParam.ProtoReflect.Descriptor instead.
+func (*QueryParam) Descriptor() ([]byte, []int) {
+	return file_proto_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *QueryParam) GetValue() isQueryParam_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *QueryParam) GetStringValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_StringValue); ok {
+			return x.StringValue
+		}
 	}
+	return ""
+}
+
+func (x *QueryParam) GetIntValue() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_IntValue); ok {
+			return x.IntValue
+		}
+	}
+	return 0
+}
+
+func (x *QueryParam) GetDoubleValue() float64 {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_DoubleValue); ok {
+			return x.DoubleValue
+		}
+	}
+	return 0
+}
+
+func (x *QueryParam) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+func (x *QueryParam) GetBytesValue() []byte {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_BytesValue); ok {
+			return x.BytesValue
+		}
+	}
+	return nil
+}
+
+func (x *QueryParam) GetNullValue() structpb.NullValue {
+	if x != nil {
+		if x, ok := x.Value.(*QueryParam_NullValue); ok {
+			return x.NullValue
+		}
+	}
+	return structpb.NullValue(0)
+}
+
+type isQueryParam_Value interface {
+	isQueryParam_Value()
+}
+
+type QueryParam_StringValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type QueryParam_IntValue struct {
+	IntValue int64 `protobuf:"varint,2,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type QueryParam_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,3,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type QueryParam_BoolValue struct {
+	BoolValue bool `protobuf:"varint,4,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type QueryParam_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,5,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+type QueryParam_NullValue struct {
+	NullValue structpb.NullValue `protobuf:"varint,6,opt,name=null_value,json=nullValue,proto3,enum=google.protobuf.NullValue,oneof"`
+}
+
+func (*QueryParam_StringValue) isQueryParam_Value() {}
+
+func (*QueryParam_IntValue) isQueryParam_Value() {}
+
+func (*QueryParam_DoubleValue) isQueryParam_Value() {}
+
+func (*QueryParam_BoolValue) isQueryParam_Value() {}
+
+func (*QueryParam_BytesValue) isQueryParam_Value() {}
+
+func (*QueryParam_NullValue) isQueryParam_Value() {}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_proto_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *QueryResponse) String() string {
@@ -102,8 +253,8 @@ func (x *QueryResponse) String() string {
 func (*QueryResponse) ProtoMessage() {}
 
 func (x *QueryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_service_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_proto_service_proto_msgTypes[2]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -115,7 +266,7 @@ func (x *QueryResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
 func (*QueryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_service_proto_rawDescGZIP(), []int{1}
+	return file_proto_service_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *QueryResponse) GetStatus() string {
@@ -141,59 +292,63 @@ func (x *QueryResponse) GetData() *structpb.Struct {
 
 var File_proto_service_proto protoreflect.FileDescriptor
 
-var file_proto_service_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1c, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74,
-	0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x3a, 0x0a, 0x0c, 0x51, 0x75,
-	0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f,
-	0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e,
-	0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x05, 0x71, 0x75, 0x65, 0x72, 0x79, 0x22, 0x6a, 0x0a, 0x0d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
-	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x2b, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x74, 0x72, 0x75, 0x63, 0x74, 0x52, 0x04, 0x64, 0x61,
-	0x74, 0x61, 0x32, 0x41, 0x0a, 0x0b, 0x44, 0x61, 0x74, 0x61, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0x12, 0x32, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x13, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x4d, 0x69, 0x6c, 0x61, 0x64, 0x2d, 0x41, 0x62, 0x6f, 0x6f, 0x61, 0x6c,
-	0x69, 0x2f, 0x64, 0x65, 0x76, 0x2d, 0x63, 0x73, 0x69, 0x72, 0x61, 0x6e, 0x2d, 0x63, 0x6f, 0x72,
-	0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
-}
+const file_proto_service_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/service.proto\x12\x05proto\x1a\x1cgoogle/protobuf/struct.proto\"e\n" +
+	"\fQueryRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12)\n" +
+	"\x06params\x18\x03 \x03(\v2\x11.proto.QueryParamR\x06params\"\xff\x01\n" +
+	"\n" +
+	"QueryParam\x12#\n" +
+	"\fstring_value\x18\x01 \x01(\tH\x00R\vstringValue\x12\x1d\n" +
+	"\tint_value\x18\x02 \x01(\x03H\x00R\bintValue\x12#\n" +
+	"\fdouble_value\x18\x03 \x01(\x01H\x00R\vdoubleValue\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x04 \x01(\bH\x00R\tboolValue\x12!\n" +
+	"\vbytes_value\x18\x05 \x01(\fH\x00R\n" +
+	"bytesValue\x12;\n" +
+	"\n" +
+	"null_value\x18\x06 \x01(\x0e2\x1a.google.protobuf.NullValueH\x00R\tnullValueB\a\n" +
+	"\x05value\"j\n" +
+	"\rQueryResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12+\n" +
+	"\x04data\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x04data2A\n" +
+	"\vDataService\x122\n" +
+	"\x05Query\x12\x13.proto.QueryRequest\x1a\x14.proto.QueryResponseB6Z4github.com/Milad-Abooali/dev-csiran-core/proto;protob\x06proto3"
 
 var (
 	file_proto_service_proto_rawDescOnce sync.Once
-	file_proto_service_proto_rawDescData = file_proto_service_proto_rawDesc
+	file_proto_service_proto_rawDescData []byte
 )
 
 func file_proto_service_proto_rawDescGZIP() []byte {
 	file_proto_service_proto_rawDescOnce.Do(func() {
-		file_proto_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_service_proto_rawDescData)
+		file_proto_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_service_proto_rawDesc), len(file_proto_service_proto_rawDesc)))
 	})
 	return file_proto_service_proto_rawDescData
 }
 
-var file_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_proto_service_proto_goTypes = []interface{}{
+var file_proto_service_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_proto_service_proto_goTypes = []any{
 	(*QueryRequest)(nil),    // 0: proto.QueryRequest
-	(*QueryResponse)(nil),   // 1: proto.QueryResponse
-	(*structpb.Struct)(nil), // 2: google.protobuf.Struct
+	(*QueryParam)(nil),      // 1: proto.QueryParam
+	(*QueryResponse)(nil),   // 2: proto.QueryResponse
+	(structpb.NullValue)(0), // 3: google.protobuf.NullValue
+	(*structpb.Struct)(nil), // 4: google.protobuf.Struct
 }
 var file_proto_service_proto_depIdxs = []int32{
-	2, // 0: proto.QueryResponse.data:type_name -> google.protobuf.Struct
-	0, // 1: proto.DataService.Query:input_type -> proto.QueryRequest
-	1, // 2: proto.DataService.Query:output_type -> proto.QueryResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	1, // 0: proto.QueryRequest.params:type_name -> proto.QueryParam
+	3, // 1: proto.QueryParam.null_value:type_name -> google.protobuf.NullValue
+	4, // 2: proto.QueryResponse.data:type_name -> google.protobuf.Struct
+	0, // 3: proto.DataService.Query:input_type -> proto.QueryRequest
+	2, // 4: proto.DataService.Query:output_type -> proto.QueryResponse
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_proto_service_proto_init() }
@@ -201,39 +356,21 @@ func file_proto_service_proto_init() {
 	if File_proto_service_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_proto_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryResponse); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
+	file_proto_service_proto_msgTypes[1].OneofWrappers = []any{
+		(*QueryParam_StringValue)(nil),
+		(*QueryParam_IntValue)(nil),
+		(*QueryParam_DoubleValue)(nil),
+		(*QueryParam_BoolValue)(nil),
+		(*QueryParam_BytesValue)(nil),
+		(*QueryParam_NullValue)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_proto_service_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_service_proto_rawDesc), len(file_proto_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -242,7 +379,6 @@ func file_proto_service_proto_init() {
 		MessageInfos:      file_proto_service_proto_msgTypes,
 	}.Build()
 	File_proto_service_proto = out.File
-	file_proto_service_proto_rawDesc = nil
 	file_proto_service_proto_goTypes = nil
 	file_proto_service_proto_depIdxs = nil
 }